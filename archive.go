@@ -0,0 +1,226 @@
+package log
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ArchiveSink receives the files a FileOutput is done writing
+// (see WithArchive)
+type ArchiveSink interface {
+	// Archive is called with the path of a rotated file, after it has been compressed
+	// if compression is enabled. Once Archive returns, the file is left as is,
+	// so Archive should move or remove it if it must not be kept.
+	Archive(path string) error
+}
+
+// WithArchive makes FileOutput hand each rotated file to sink, in the background
+// and after compression if enabled.
+//
+// Failures are reported to ErrorHandler.
+func WithArchive(sink ArchiveSink) FileOption {
+	return func(o *FileOutput) {
+		o.archive = sink
+	}
+}
+
+// name of the file recording what a DirArchive archived
+const archiveRecord = "archive.log"
+
+// DirArchive is an ArchiveSink moving files into a directory tree
+// (Root/year/month/day/name, after the modification time of files).
+//
+// A sha256 checksum of each file is verified after moving it and saved next
+// to it (name.sha256, in sha256sum format), and every archived file is recorded
+// as a JSON line in Root/archive.log.
+type DirArchive struct {
+	Root string
+
+	// number of attempts after a failed one
+	Retries int
+
+	// wait before the first retry, doubled after each retry
+	Backoff time.Duration
+
+	mu sync.Mutex
+}
+
+// NewDirArchive returns a DirArchive moving files under root
+// and retrying 3 times starting with a 100ms backoff
+func NewDirArchive(root string) *DirArchive {
+	return &DirArchive{
+		Root:    root,
+		Retries: 3,
+		Backoff: 100 * time.Millisecond,
+	}
+}
+
+func (a *DirArchive) Archive(path string) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// steps are retried on their own as path is gone once moved
+	var info os.FileInfo
+	var sum string
+	err := a.retry(func() error {
+		var err error
+		if info, err = os.Stat(path); err != nil {
+			return err
+		}
+		sum, err = checksum(path)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	t := info.ModTime()
+	dir := filepath.Join(a.Root, strconv.Itoa(t.Year()), fmt.Sprintf("%02d", t.Month()), fmt.Sprintf("%02d", t.Day()))
+	var dest string
+	err = a.retry(func() error {
+		if dest == "" {
+			if err := os.MkdirAll(dir, 0775); err != nil {
+				return err
+			}
+			dest = filepath.Join(dir, filepath.Base(path))
+			for i := 1; exists(dest); i++ {
+				dest = filepath.Join(dir, strconv.Itoa(i)+"-"+filepath.Base(path))
+			}
+		}
+		return move(path, dest, sum)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = a.retry(func() error {
+		return os.WriteFile(dest+".sha256", []byte(sum+"  "+filepath.Base(dest)+"\n"), 0664)
+	})
+	// the file is recorded even without its checksum file
+	if e := a.retry(func() error {
+		return a.record(M{
+			{"time", time.Now()},
+			{"source", path},
+			{"path", dest},
+			{"size", info.Size()},
+			{"sha256", sum},
+		})
+	}); err == nil {
+		err = e
+	}
+	return err
+}
+
+// retry calls fn until it succeeds or a.Retries retries failed
+func (a *DirArchive) retry(fn func() error) error {
+	var err error
+	var backoff = a.Backoff
+	for i := 0; i <= a.Retries; i++ {
+		if i != 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		if err = fn(); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// record appends m to the record of archived files
+func (a *DirArchive) record(m M) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(a.Root, archiveRecord), appendFlags, 0664)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// checksum returns the hex encoded sha256 checksum of the file at path
+func checksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// move renames src to dst, copying it if they are not on the same filesystem,
+// in which case src is only removed once the copy matches sum.
+//
+// move can be called again after failing, dst is never removed once src is gone.
+func move(src, dst, sum string) error {
+	if !exists(src) {
+		// moved by a previous call
+		return verify(dst, sum)
+	}
+	if os.Rename(src, dst) == nil {
+		return verify(dst, sum)
+	}
+	if !exists(dst) || verify(dst, sum) != nil {
+		// src is still there
+		os.Remove(dst)
+		if err := copyFile(src, dst); err != nil {
+			os.Remove(dst)
+			return err
+		}
+		if err := verify(dst, sum); err != nil {
+			os.Remove(dst)
+			return err
+		}
+	}
+	return os.Remove(src)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0664)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if e := out.Sync(); err == nil {
+		err = e
+	}
+	if e := out.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// verify checks that the sha256 checksum of the file at path is sum
+func verify(path, sum string) error {
+	s, err := checksum(path)
+	if err != nil {
+		return err
+	}
+	if s != sum {
+		return fmt.Errorf("checksum mismatch for %v", path)
+	}
+	return nil
+}
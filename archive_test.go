@@ -0,0 +1,69 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirArchive(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "archive")
+	src := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(src, []byte("some entries\n"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2022, 12, 20, 12, 43, 5, 0, time.Local)
+	if err := os.Chtimes(src, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	a := NewDirArchive(root)
+	if err := a.Archive(src); err != nil {
+		t.Fatal(err)
+	}
+	if exists(src) {
+		t.Error("source file is still there")
+	}
+	dest := filepath.Join(root, "2022", "12", "20", "app.log")
+	if data, err := os.ReadFile(dest); err != nil || string(data) != "some entries\n" {
+		t.Fatalf("archived file = %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(dest + ".sha256"); err != nil || !strings.HasSuffix(string(data), "  app.log\n") {
+		t.Fatalf("checksum file = %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(root, archiveRecord)); err != nil || !strings.Contains(string(data), `"path":`) {
+		t.Fatalf("record = %q, %v", data, err)
+	}
+}
+
+func TestDirArchiveRecordFailure(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "archive")
+	src := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(src, []byte("some entries\n"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	// the record can not be opened
+	if err := os.MkdirAll(filepath.Join(root, archiveRecord), 0775); err != nil {
+		t.Fatal(err)
+	}
+
+	a := &DirArchive{Root: root, Retries: 2, Backoff: time.Millisecond}
+	err := a.Archive(src)
+	if err == nil || !strings.Contains(err.Error(), archiveRecord) {
+		t.Fatalf("Archive = %v, want an error about the record", err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(root, "*", "*", "*", "*app.log"))
+	if len(matches) != 1 {
+		t.Fatalf("archived files = %q, want one", matches)
+	}
+	if data, err := os.ReadFile(matches[0]); err != nil || string(data) != "some entries\n" {
+		t.Fatalf("archived file = %q, %v", data, err)
+	}
+	if !exists(matches[0] + ".sha256") {
+		t.Error("checksum file is missing")
+	}
+}
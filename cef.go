@@ -0,0 +1,172 @@
+package log
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CEFConfig holds the header values of lines written by a LogFunc returned by NewCEFLogFunc
+type CEFConfig struct {
+	Vendor  string
+	Product string
+	Version string
+
+	// device event class of entries without prefix ("log" by default)
+	Class string
+}
+
+// CEFSeverity returns the CEF severity (0-10) of level
+func CEFSeverity(level LogLevel) int {
+	switch level {
+	case L_Debug:
+		return 1
+	case L_Info:
+		return 3
+	case L_Warn:
+		return 5
+	case L_Error:
+		return 8
+	case L_Fatal:
+		return 10
+	default:
+		return 0
+	}
+}
+
+// NewCEFLogFunc returns a LogFunc writing entries as Common Event Format lines, along with
+// the OutputType it adds buffers with:
+//
+//	CEF:0|Vendor|Product|1.0|app.db|some message|3|rt=1671536585123 key=value
+//
+// Prefixes are joined with '.' into the device event class (or only the last one with F_LastPrefix),
+// the message is the event name and the severity is given by CEFSeverity.
+//
+// Time is written as the 'rt' extension (milliseconds since epoch) with F_Time and fields are added
+// as extensions with any F_Fields_* flag, keys being stripped of any character other than letters,
+// digits and '_'. Header values and extensions are escaped as required by CEF. Fields
+// whose key is 'rt' once stripped are left out with F_Time (see ErrFieldCollision).
+func NewCEFLogFunc(cfg CEFConfig) (func(*[]byte, *LogEntry, int, io.Writer) error, OutputType) {
+	setDefault(&cfg.Class, "log")
+	var header = make([]byte, 0, 64)
+	header = append(header, "CEF:0|"...)
+	appendCEFHeader(&header, cfg.Vendor)
+	header = append(header, '|')
+	appendCEFHeader(&header, cfg.Product)
+	header = append(header, '|')
+	appendCEFHeader(&header, cfg.Version)
+	header = append(header, '|')
+
+	outputType := NewOutputType()
+	var collided sync.Once
+	return func(buf *[]byte, entry *LogEntry, flags int, w io.Writer) error {
+		*buf = append(*buf, header...)
+		if len(entry.Prefixes) != 0 && flags&(F_Prefix|F_LastPrefix) != 0 {
+			if flags&F_LastPrefix != 0 {
+				appendCEFHeader(buf, entry.Prefixes[len(entry.Prefixes)-1])
+			} else {
+				appendCEFHeader(buf, strings.Join(entry.Prefixes, "."))
+			}
+		} else {
+			appendCEFHeader(buf, cfg.Class)
+		}
+		*buf = append(*buf, '|')
+		appendCEFHeader(buf, strings.TrimSuffix(entry.Msg, "\n"))
+		*buf = append(*buf, '|')
+		*buf = strconv.AppendInt(*buf, int64(CEFSeverity(entry.Level)), 10)
+		*buf = append(*buf, '|')
+
+		var sep, rt bool
+		if flags&(F_Time|F_Micro) != 0 {
+			*buf = append(*buf, "rt="...)
+			*buf = strconv.AppendInt(*buf, entry.Time.UnixMilli(), 10)
+			sep, rt = true, true
+		}
+		if flags&(F_Fields|F_Fields_A|F_Fields_B) != 0 {
+			for _, v := range entry.Fields {
+				var start = len(*buf)
+				if sep {
+					*buf = append(*buf, ' ')
+				}
+				var key = len(*buf)
+				appendCEFKey(buf, v.Key)
+				if rt && string((*buf)[key:]) == "rt" {
+					*buf = (*buf)[:start]
+					reportCollision(&collided, v.Key)
+					continue
+				}
+				sep = true
+				*buf = append(*buf, '=')
+				appendCEFValue(buf, fieldString(v.Val))
+			}
+		}
+
+		if flags&F_NewLine != 0 {
+			*buf = append(*buf, '\n')
+		}
+
+		entry.AddCompiled(flags, outputType, buf)
+		_, err := w.Write(*buf)
+		return err
+	}, outputType
+}
+
+// NewCEFOutput returns an Output writing entries as Common Event Format lines
+// (see NewCEFLogFunc)
+func NewCEFOutput(w io.Writer, flags int, cfg CEFConfig, close bool) Output {
+	logFunc, outputType := NewCEFLogFunc(cfg)
+	return &outputWrapper{
+		w:          w,
+		close:      close,
+		flags:      flags,
+		logLevel:   L_Info,
+		outputType: outputType,
+		LogFunc:    logFunc,
+		CloseFunc:  DefaultCloseFunc,
+	}
+}
+
+// appendCEFHeader appends a header value escaping '\' and '|',
+// line breaks are replaced by spaces as they are not allowed in headers
+func appendCEFHeader(buf *[]byte, s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '|':
+			*buf = append(*buf, '\\', c)
+		case '\n', '\r':
+			*buf = append(*buf, ' ')
+		default:
+			*buf = append(*buf, c)
+		}
+	}
+}
+
+// appendCEFValue appends an extension value escaping '\', '=' and line breaks
+func appendCEFValue(buf *[]byte, s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '=':
+			*buf = append(*buf, '\\', c)
+		case '\n':
+			*buf = append(*buf, `\n`...)
+		case '\r':
+			*buf = append(*buf, `\r`...)
+		default:
+			*buf = append(*buf, c)
+		}
+	}
+}
+
+// appendCEFKey appends key without characters not allowed in extension keys
+func appendCEFKey(buf *[]byte, key string) {
+	var start = len(*buf)
+	for i := 0; i < len(key); i++ {
+		if c := key[i]; c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' {
+			*buf = append(*buf, c)
+		}
+	}
+	if len(*buf) == start {
+		*buf = append(*buf, '_')
+	}
+}
@@ -0,0 +1,48 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCEF(t *testing.T) {
+	var errs []error
+	defer func(h func(error)) { ErrorHandler = h }(ErrorHandler)
+	ErrorHandler = func(err error) { errs = append(errs, err) }
+
+	fn, _ := NewCEFLogFunc(CEFConfig{Vendor: "Ac|me", Product: `pro\d`, Version: "1.0"})
+	fields := M{{"k", "a=b"}, {"path", `c:\x`}, {"multi", "l1\nl2\r"}, {"r t", "x"}, {"é!", 1}}
+	at := time.Date(2022, 12, 20, 12, 43, 5, 123e6, time.UTC)
+	for _, tt := range []struct {
+		flags int
+		entry *LogEntry
+		want  string
+	}{
+		{F_Time | F_Prefix | F_Fields | F_NewLine,
+			&LogEntry{Time: at, Level: L_Error, Msg: "a|b\\c\nd\n", Fields: fields},
+			`CEF:0|Ac\|me|pro\\d|1.0|log|a\|b\\c d|8|rt=1671540185123 k=a\=b path=c:\\x multi=l1\nl2\r _=1` + "\n"},
+		// rt is only reserved with F_Time
+		{F_LastPrefix | F_Fields,
+			&LogEntry{Level: L_Info, Msg: "m", Prefixes: []string{"app", "d|b"}, Fields: M{{"rt", "x"}}},
+			`CEF:0|Ac\|me|pro\\d|1.0|d\|b|m|3|rt=x`},
+		{F_Time | F_Fields,
+			&LogEntry{Time: at, Level: L_Info, Msg: "m", Fields: M{{"rt", "x"}}},
+			`CEF:0|Ac\|me|pro\\d|1.0|log|m|3|rt=1671540185123`},
+		{F_Fields,
+			&LogEntry{Level: L_Info, Msg: "m", Fields: M{{"a", 1}}},
+			`CEF:0|Ac\|me|pro\\d|1.0|log|m|3|a=1`},
+	} {
+		var w bytes.Buffer
+		if err := fn(tt.entry.GetBuf(), tt.entry, tt.flags, &w); err != nil {
+			t.Fatal(err)
+		}
+		if w.String() != tt.want {
+			t.Errorf("got  %s\nwant %s", w.String(), tt.want)
+		}
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrFieldCollision) {
+		t.Errorf("errors = %v, want one ErrFieldCollision", errs)
+	}
+}
@@ -0,0 +1,244 @@
+package log
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// WithCompress makes FileOutput gzip its files once they are rotated, in the
+// background. Compressed files are named after the rotated file with a '.gz'
+// suffix and the uncompressed file is removed.
+//
+// Failures are reported to ErrorHandler and leave the rotated file untouched.
+func WithCompress() FileOption {
+	return func(o *FileOutput) {
+		o.compress = true
+	}
+}
+
+// rotated hands a file that won't be written anymore to a background goroutine
+// doing any work configured on such files.
+//
+// o.mu must be held.
+func (o *FileOutput) rotated(path string) {
+	if !o.compress && o.archive == nil && o.retention == nil {
+		return
+	}
+	o.background(func() {
+		if o.lock {
+			// every process sharing the file queues this work
+			release, ok := claim(path)
+			if !ok {
+				return
+			}
+			defer release()
+		}
+		// path might have been expired or handled by another process while waiting
+		if exists(path) {
+			if o.compress {
+				name, err := o.compressFile(path)
+				if err != nil {
+					reportError(err)
+				}
+				if name != "" {
+					path = name
+				}
+			}
+			if o.archive != nil {
+				if err := o.archive.Archive(path); err != nil {
+					reportError(fmt.Errorf("archiving %v: %w", path, err))
+				}
+			}
+		}
+		if o.retention != nil {
+			o.retain()
+		}
+	})
+}
+
+// background queues fn to be run in a background goroutine. Functions are
+// run one at a time, in order, and LogClose waits for them to return.
+func (o *FileOutput) background(fn func()) {
+	o.post.Lock()
+	defer o.post.Unlock()
+	o.jobs = append(o.jobs, fn)
+	if len(o.jobs) > 1 {
+		// already running
+		return
+	}
+	o.postWg.Add(1)
+	go func() {
+		defer o.postWg.Done()
+		o.post.Lock()
+		for len(o.jobs) != 0 {
+			fn := o.jobs[0]
+			o.post.Unlock()
+			fn()
+			o.post.Lock()
+			o.jobs = o.jobs[1:]
+		}
+		o.post.Unlock()
+	}()
+}
+
+// compressFile gzips path into path.gz and removes path, it returns the path of
+// the compressed file. path.gz is created with the permissions of the files of o.
+//
+// The archive is written under a temporary name so that path.gz is never
+// a partial archive.
+func (o *FileOutput) compressFile(path string) (string, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("compressing %v: %w", path, err)
+	}
+	defer src.Close()
+
+	name := path + ".gz"
+	if exists(name) {
+		// path has been created again after being compressed
+		return "", fmt.Errorf("compressing %v: %v already exists", path, name)
+	}
+	tmp := name + ".tmp"
+	// left by an interrupted compression, or planted: it is never followed
+	os.Remove(tmp)
+	var osFlags = os.O_CREATE | os.O_EXCL | os.O_WRONLY
+	if o.noFollow {
+		osFlags |= oNoFollow
+	}
+	err = writeGzip(tmp, osFlags, o.perm, src)
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("compressing %v: %w", path, err)
+	}
+	src.Close()
+	if err := os.Remove(path); err != nil {
+		return name, fmt.Errorf("compressing %v: %w", path, err)
+	}
+	return name, nil
+}
+
+func writeGzip(path string, osFlags int, perm os.FileMode, src *os.File) error {
+	dst, err := os.OpenFile(path, osFlags, perm)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	zw.Name = filepath.Base(src.Name())
+	if info, err := src.Stat(); err == nil {
+		zw.ModTime = info.ModTime()
+	}
+	_, err = io.Copy(zw, src)
+	if e := zw.Close(); err == nil {
+		err = e
+	}
+	if e := dst.Sync(); err == nil {
+		err = e
+	}
+	if e := dst.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+type flushWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// compressOutput is an outputWrapper writing to a compressor
+// that is flushed periodically
+type compressOutput struct {
+	*outputWrapper
+	mu     sync.Mutex
+	zw     flushWriter
+	stop   chan struct{}
+	closed bool
+}
+
+// NewGzipOutput returns an Output writing a gzip stream to w, formatted
+// according to outputType (Text by default).
+//
+// The stream is flushed every flush (if flush > 0) so that everything written
+// before the last flush can be decompressed even if the stream is never closed.
+//
+// 'LogClose()' ends the stream and closes w if close is true.
+func NewGzipOutput(w io.Writer, flags int, outputType OutputType, flush time.Duration, close bool) Output {
+	return newCompressOutput(w, gzip.NewWriter(w), flags, outputType, flush, close)
+}
+
+// NewFlateOutput is similar to NewGzipOutput but writes a raw DEFLATE stream (RFC 1951).
+func NewFlateOutput(w io.Writer, flags int, outputType OutputType, flush time.Duration, close bool) Output {
+	zw, _ := flate.NewWriter(w, flate.DefaultCompression)
+	return newCompressOutput(w, zw, flags, outputType, flush, close)
+}
+
+func newCompressOutput(w io.Writer, zw flushWriter, flags int, outputType OutputType, flush time.Duration, closeW bool) Output {
+	logFunc, outputType := logFunc(outputType)
+	o := &compressOutput{
+		outputWrapper: &outputWrapper{
+			w:          zw,
+			close:      closeW,
+			flags:      flags,
+			logLevel:   L_Info,
+			outputType: outputType,
+			LogFunc:    logFunc,
+		},
+		zw:   zw,
+		stop: make(chan struct{}),
+	}
+	// the compressor is handed to CloseFunc, not w
+	o.CloseFunc = func(_ io.Writer, closeW bool) error {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if o.closed {
+			return nil
+		}
+		o.closed = true
+		close(o.stop)
+		err := o.zw.Close()
+		if e := DefaultCloseFunc(w, closeW); err == nil {
+			err = e
+		}
+		return err
+	}
+	if flush > 0 {
+		go o.flushPeriodically(flush)
+	}
+	return o
+}
+
+func (o *compressOutput) Log(entry *LogEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrOutputClosed
+	}
+	return o.outputWrapper.Log(entry)
+}
+
+func (o *compressOutput) flushPeriodically(d time.Duration) {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			o.mu.Lock()
+			err := o.zw.Flush()
+			o.mu.Unlock()
+			if err != nil {
+				reportError(fmt.Errorf("flushing compressed stream: %w", err))
+			}
+		case <-o.stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package log
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func TestGzipOutput(t *testing.T) {
+	var buf bytes.Buffer
+	out := NewGzipOutput(&buf, F_Level|F_NewLine, T_Text, 0, false)
+	l := NewLogger().Sync()
+	l.AddOutput(out)
+	l.Info("one")
+	l.Warn("two")
+	l.Close()
+	// closed by the caller too
+	if err := out.LogClose(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Log(&LogEntry{Level: L_Info, Msg: "late"}); err != ErrOutputClosed {
+		t.Errorf("Log after close = %v, want %v", err, ErrOutputClosed)
+	}
+
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "[INFO] one\n[WARN] two\n"; string(data) != want {
+		t.Errorf("data = %q, want %q", data, want)
+	}
+}
@@ -1,5 +1,7 @@
 package log
 
+import "sync/atomic"
+
 type LogLevel int
 
 func (l LogLevel) String() string {
@@ -47,8 +49,24 @@ type OutputType int
 const (
 	T_Text OutputType = iota
 	T_JSON
+	T_Logfmt
+	T_ECS
 )
 
+// first OutputType returned by NewOutputType
+const firstOutputType = 1 << 16
+
+var lastOutputType int64 = firstOutputType - 1
+
+// NewOutputType returns a new OutputType, distinct from builtin ones and from
+// any other returned by NewOutputType.
+//
+// It is intended for outputs that are configured at runtime (ex: CompilePattern),
+// for which buffers compiled with different configurations must not be shared.
+func NewOutputType() OutputType {
+	return OutputType(atomic.AddInt64(&lastOutputType, 1))
+}
+
 const (
 	L_Debug LogLevel = iota
 	L_Info
@@ -57,10 +75,13 @@ const (
 	L_Fatal
 )
 
+// keys used by JSONLogFunc
 const (
 	TimeFieldKey   = "time"
 	LevelFieldKey  = "level"
 	PrefixFieldKey = "prefix"
+	MsgFieldKey    = "msg"
+	FieldsFieldKey = "fields"
 )
 
 const (
@@ -83,19 +104,22 @@ const (
 	// wether to add '\n' at the end of each line if not already present (or always add it in case of T_JSON)
 	F_NewLine
 
-	// wether to add fields as top level fields in marshaled structure; ignored by T_TEXT
+	// wether to add fields as top level fields in marshaled structure; in the case
+	// of T_Text fields are added after the message as 'key=value' pairs
 	//
 	// if multiple F_Fields_* are added the priority is F_Fields_A -> F_Fields -> F_Fields_B
 	F_Fields
 
 	// similar to F_Fields but fields are marshaled as JSON object in
-	// a single fields named 'fields' instead of top level fields
+	// a single fields named 'fields' instead of top level fields; in the case
+	// of T_Text fields are added after the message as '{key: value, ...}'
 	//
 	// if multiple F_Fields_* are added the priority is F_Fields_A -> F_Fields -> F_Fields_B
 	F_Fields_A
 
 	// similar to F_Fields but fields are marshaled as an array of objects in
-	// a single field named 'fields' instead of top level fields
+	// a single field named 'fields' instead of top level fields; in the case
+	// of T_Text fields are added after the message as '[key=value] ...'
 	//
 	// if multiple F_Fields_* are added the priority is F_Fields_A -> F_Fields -> F_Fields_B
 	F_Fields_B
@@ -0,0 +1,132 @@
+package log
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// NewCSVLogFunc returns a LogFunc writing entries as CSV records (RFC 4180) with columns
+// time, level, prefix, msg, one column per key of keys and extra, along with the OutputType it
+// adds buffers with.
+//
+// Every record has the same columns, the ones disabled by flags being left empty; F_NewLine is
+// ignored as records always end with "\r\n". Prefixes are joined with '.' (or only the last one with F_LastPrefix).
+//
+// With any F_Fields_* flag, fields whose key is in keys are written in their column and other
+// fields are written in extra as a JSON object.
+//
+// An error is returned if a key is given twice or is the name of another column.
+func NewCSVLogFunc(keys []string) (func(*[]byte, *LogEntry, int, io.Writer) error, OutputType, error) {
+	var columns = make(map[string]int, len(keys))
+	for i, k := range keys {
+		switch k {
+		case "time", "level", "prefix", "msg", "extra":
+			return nil, 0, fmt.Errorf("csv keys: %q is the name of another column", k)
+		}
+		if _, ok := columns[k]; ok {
+			return nil, 0, fmt.Errorf("csv keys: key %q is used more than once", k)
+		}
+		columns[k] = 4 + i
+	}
+	var n = 5 + len(keys)
+	outputType := NewOutputType()
+	return func(buf *[]byte, entry *LogEntry, flags int, w io.Writer) error {
+		var record = make([]string, n)
+		if flags&(F_Time|F_Micro) != 0 {
+			if flags&F_Micro != 0 {
+				record[0] = entry.Time.Format("2006-01-02T15:04:05.000000Z07:00")
+			} else {
+				record[0] = entry.Time.Format(time.RFC3339)
+			}
+		}
+		if flags&F_Level != 0 {
+			record[1] = entry.Level.String()
+		}
+		if len(entry.Prefixes) != 0 && flags&(F_Prefix|F_LastPrefix) != 0 {
+			if flags&F_LastPrefix != 0 {
+				record[2] = entry.Prefixes[len(entry.Prefixes)-1]
+			} else {
+				record[2] = strings.Join(entry.Prefixes, ".")
+			}
+		}
+		record[3] = strings.TrimSuffix(entry.Msg, "\n")
+
+		if flags&(F_Fields|F_Fields_A|F_Fields_B) != 0 {
+			var extra M
+			for _, v := range entry.Fields {
+				if i, ok := columns[v.Key]; ok {
+					record[i] = fieldString(v.Val)
+				} else {
+					extra.AddE(v)
+				}
+			}
+			if len(extra) != 0 {
+				data, err := json.Marshal(extra)
+				if err != nil {
+					entry.AddCompiled(F_NotSave, outputType, buf)
+					return err
+				}
+				record[n-1] = string(data)
+			}
+		}
+
+		if err := writeCSV(buf, record); err != nil {
+			entry.AddCompiled(F_NotSave, outputType, buf)
+			return err
+		}
+		entry.AddCompiled(flags, outputType, buf)
+		_, err := w.Write(*buf)
+		return err
+	}, outputType, nil
+}
+
+// NewCSVOutput returns an Output writing entries as CSV records (see NewCSVLogFunc),
+// the header row is written to w before returning.
+func NewCSVOutput(w io.Writer, flags int, keys []string, close bool) (Output, error) {
+	logFunc, outputType, err := NewCSVLogFunc(keys)
+	if err != nil {
+		return nil, err
+	}
+	var header = append([]string{"time", "level", "prefix", "msg"}, keys...)
+	var buf []byte
+	if err := writeCSV(&buf, append(header, "extra")); err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(buf); err != nil {
+		return nil, err
+	}
+	return &outputWrapper{
+		w:          w,
+		close:      close,
+		flags:      flags,
+		logLevel:   L_Info,
+		outputType: outputType,
+		LogFunc:    logFunc,
+		CloseFunc:  DefaultCloseFunc,
+	}, nil
+}
+
+// bufWriter appends everything written to buf
+type bufWriter struct {
+	buf *[]byte
+}
+
+func (w bufWriter) Write(p []byte) (int, error) {
+	*w.buf = append(*w.buf, p...)
+	return len(p), nil
+}
+
+// writeCSV appends record to buf as a CSV line
+func writeCSV(buf *[]byte, record []string) error {
+	cw := csv.NewWriter(bufWriter{buf})
+	cw.UseCRLF = true
+	if err := cw.Write(record); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,34 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCSVOutput(t *testing.T) {
+	var w bytes.Buffer
+	out, err := NewCSVOutput(&w, F_Level|F_Prefix|F_Fields, []string{"user", "status"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := &LogEntry{Level: L_Warn, Msg: "a \"quoted\", msg\n", Prefixes: []string{"app", "db"},
+		Fields: M{{"status", 404}, {"path", "/x"}, {"user", "bob"}}}
+	if err := out.Log(entry); err != nil {
+		t.Fatal(err)
+	}
+	want := "time,level,prefix,msg,user,status,extra\r\n" +
+		`,WARN,app.db,"a ""quoted"", msg",bob,404,"{""path"":""/x""}"` + "\r\n"
+	if w.String() != want {
+		t.Errorf("got %q, want %q", w.String(), want)
+	}
+}
+
+func TestCSVKeys(t *testing.T) {
+	for _, keys := range [][]string{
+		{"time"}, {"level"}, {"prefix"}, {"msg"}, {"extra"}, {"user", "status", "user"},
+	} {
+		if _, err := NewCSVOutput(&bytes.Buffer{}, F_Std, keys, false); err == nil {
+			t.Errorf("NewCSVOutput(%q): expected an error", keys)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package log
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrDiskGuardUnsupported is returned by NewFileOutput when WithDiskGuard is used
+// on a platform where free disk space can not be checked
+var ErrDiskGuardUnsupported = fmt.Errorf("disk guard is not supported on this platform")
+
+// DiskGuard describes what FileOutput does while free space on the
+// filesystem of its file is below MinFree bytes.
+//
+// A single warning entry is recorded when the guard trips and when it recovers.
+type DiskGuard struct {
+	// free space in bytes below which the guard trips
+	MinFree uint64
+
+	// entries below Level are dropped while the guard is tripped
+	Level LogLevel
+
+	// wether to run the retention policy (see WithRetention) when the guard trips
+	Cleanup bool
+
+	// if non-nil, entries that are not dropped are logged to Fallback instead of
+	// the file while the guard is tripped.
+	//
+	// If Fallback returns ErrOutputClosed, entries are written to the file from then on.
+	//
+	// NOTE: Fallback is not closed by FileOutput
+	Fallback Output
+
+	// how often free space is checked (defaults to a second)
+	Interval time.Duration
+}
+
+// WithDiskGuard makes FileOutput check free space on the filesystem of its file
+// and apply guard when it gets low.
+//
+// It is only supported on Linux.
+func WithDiskGuard(guard DiskGuard) FileOption {
+	if guard.Interval <= 0 {
+		guard.Interval = time.Second
+	}
+	return func(o *FileOutput) {
+		o.guard = &guard
+	}
+}
+
+// guardDisk checks free space if needed and applies o.guard to entry,
+// done is true if entry must not be written to the file.
+//
+// o.mu must be held.
+func (o *FileOutput) guardDisk(entry *LogEntry) (done bool, err error) {
+	g := o.guard
+	if now := time.Now(); now.Sub(o.lastCheck) >= g.Interval {
+		o.lastCheck = now
+		free, err := diskFree(filepath.Dir(o.path))
+		if err != nil {
+			return false, fmt.Errorf("checking disk space: %w", err)
+		}
+		if tripped := free < g.MinFree; tripped != o.tripped {
+			o.tripped = tripped
+			o.warnDisk(entry, free)
+		}
+	}
+	if !o.tripped {
+		return false, nil
+	}
+	if !g.Level.Permits(entry.Level) {
+		return true, nil
+	}
+	if ok, err := o.fallback(entry); ok {
+		return true, err
+	}
+	return false, nil
+}
+
+// fallback logs entry to o.guard.Fallback, ok is false if there is no fallback
+// or if it has been closed, in which case entry must be written to the file.
+//
+// o.mu must be held.
+func (o *FileOutput) fallback(entry *LogEntry) (ok bool, err error) {
+	if o.guard.Fallback == nil || o.fallbackClosed {
+		return false, nil
+	}
+	err = o.guard.Fallback.Log(entry)
+	if err == ErrOutputClosed {
+		// ErrOutputClosed must not be returned as it would remove o from its loggers
+		o.fallbackClosed = true
+		return false, nil
+	}
+	return true, err
+}
+
+// warnDisk records a warning entry about o.guard state changing
+// and triggers cleanup if the guard tripped.
+//
+// o.mu must be held.
+func (o *FileOutput) warnDisk(entry *LogEntry, free uint64) {
+	var warning = &LogEntry{
+		Time:     time.Now(),
+		Prefixes: entry.Prefixes,
+		Level:    L_Warn,
+		Fields:   M{{"free", free}, {"min_free", o.guard.MinFree}, {"path", o.path}},
+		Compiled: []Compiled{},
+		Mutex:    sync.Mutex{},
+	}
+
+	var err error
+	if o.tripped {
+		warning.Msg = "free disk space is low"
+		if o.guard.Level > L_Debug {
+			warning.Msg += ", entries below " + o.guard.Level.String() + " are dropped"
+		}
+		var ok bool
+		if ok, err = o.fallback(warning); !ok {
+			err = o.write(warning)
+		}
+		if o.guard.Cleanup && o.retention != nil {
+			o.background(o.retain)
+		}
+	} else {
+		warning.Msg = "free disk space is back to normal"
+		err = o.write(warning)
+	}
+	if err != nil {
+		reportError(err)
+	}
+	for _, c := range warning.Compiled {
+		putBuf(c.Buf)
+	}
+}
@@ -0,0 +1,15 @@
+package log
+
+import "syscall"
+
+const diskGuardSupported = true
+
+// diskFree returns the amount of bytes available to unprivileged
+// users on the filesystem of path
+func diskFree(path string) (uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return st.Bavail * uint64(st.Bsize), nil
+}
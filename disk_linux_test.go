@@ -0,0 +1,35 @@
+package log
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiskGuardClosedFallback(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	fallback := NewOutputWrapper(io.Discard, false, F_Std, T_Text, L_Debug, func(*[]byte, *LogEntry, int, io.Writer) error {
+		return ErrOutputClosed
+	}, nil)
+	out, err := NewFileOutput(path, false, F_Level|F_NewLine, L_Info, T_Text, true,
+		WithDiskGuard(DiskGuard{MinFree: math.MaxUint64, Level: L_Info, Fallback: fallback}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.LogClose()
+
+	entry := &LogEntry{Level: L_Info, Msg: "kept"}
+	if err := out.Log(entry); err != nil {
+		t.Fatalf("Log = %v, want nil", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(data), "[INFO] kept\n") {
+		t.Errorf("file = %q", data)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package log
+
+const diskGuardSupported = false
+
+func diskFree(path string) (uint64, error) {
+	return 0, ErrDiskGuardUnsupported
+}
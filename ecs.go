@@ -0,0 +1,119 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// version of the Elastic Common Schema written by ECSLogFunc
+const ECSVersion = "8.11.0"
+
+// ECSLogFunc formats entry with specified flags and writes it to w as a JSON object
+// following the Elastic Common Schema, adding buf to entry with ECS output type:
+//
+//	{"@timestamp":"2022-12-20T12:43:05.123Z","log.level":"info","message":"msg",
+//	"ecs.version":"8.11.0","log.logger":"app.db","labels":{"key":"value"}}
+//
+// Prefixes are joined with '.' into log.logger (or only the last one with F_LastPrefix).
+//
+// Fields are added with any F_Fields_* flag: the first field holding an error is written as
+// error.message and error.type, fields holding an M are written as nested objects, fields
+// whose key contains a '.' are assumed to be ECS fields and written as is, and
+// other fields are written as strings in labels.
+//
+// Nested and ECS fields whose key is one written by ECSLogFunc (ex: log.level or error)
+// are left out (see ErrFieldCollision).
+func ECSLogFunc(buf *[]byte, entry *LogEntry, flags int, w io.Writer) error {
+	var m = M{}
+	if flags&F_Time != 0 {
+		m.Add("@timestamp", entry.Time.UTC().Format(time.RFC3339Nano))
+	}
+	if flags&F_Level != 0 {
+		m.Add("log.level", strings.ToLower(entry.Level.String()))
+	}
+	m.Add("message", entry.Msg)
+	m.Add("ecs.version", ECSVersion)
+
+	if len(entry.Prefixes) != 0 && flags&(F_Prefix|F_LastPrefix) != 0 {
+		if flags&F_LastPrefix != 0 {
+			m.Add("log.logger", entry.Prefixes[len(entry.Prefixes)-1])
+		} else {
+			m.Add("log.logger", strings.Join(entry.Prefixes, "."))
+		}
+	}
+
+	if len(entry.Fields) != 0 && flags&(F_Fields|F_Fields_A|F_Fields_B) != 0 {
+		var labels, other M
+		var hasErr bool
+		for _, v := range entry.Fields {
+			switch val := v.Val.(type) {
+			case error:
+				if !hasErr {
+					hasErr = true
+					m.Add("error", M{{"message", val.Error()}, {"type", fmt.Sprintf("%T", val)}})
+					continue
+				}
+			case M:
+				if ecsReserved(v.Key) {
+					reportCollision(&ecsCollided, v.Key)
+				} else {
+					other.Add(v.Key, val)
+				}
+				continue
+			}
+			if strings.Contains(v.Key, ".") {
+				if ecsReserved(v.Key) {
+					reportCollision(&ecsCollided, v.Key)
+				} else {
+					other.AddE(v)
+				}
+			} else {
+				labels.Add(v.Key, fieldString(v.Val))
+			}
+		}
+		if len(labels) != 0 {
+			m.Add("labels", labels)
+		}
+		m.AddE(other...)
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		entry.AddCompiled(F_NotSave, T_ECS, buf)
+		return err
+	}
+	if flags&F_NewLine != 0 {
+		data = append(data, '\n')
+	}
+	entry.AddCompiled(flags, T_ECS, &data)
+	entry.AddCompiled(F_NotSave, T_ECS, buf)
+	_, err = w.Write(data)
+	return err
+}
+
+var ecsCollided sync.Once
+
+// ecsReserved returns wether key is a top level key written by ECSLogFunc
+func ecsReserved(key string) bool {
+	switch key {
+	case "@timestamp", "log.level", "message", "ecs.version", "log.logger", "labels", "error":
+		return true
+	}
+	return false
+}
+
+func NewECSOutput(w io.Writer, flags int, close bool) Output {
+	return &outputWrapper{
+		w:          w,
+		close:      close,
+		flags:      flags,
+		logLevel:   L_Info,
+		outputType: T_ECS,
+		LogFunc:    ECSLogFunc,
+		CloseFunc:  DefaultCloseFunc,
+	}
+}
@@ -0,0 +1,49 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestECS(t *testing.T) {
+	var errs []error
+	defer func(h func(error)) { ErrorHandler = h }(ErrorHandler)
+	ErrorHandler = func(err error) { errs = append(errs, err) }
+	ecsCollided = sync.Once{}
+
+	var w bytes.Buffer
+	out := NewECSOutput(&w, F_Std, false)
+	for i := 0; i < 2; i++ {
+		entry := &LogEntry{
+			Time:     time.Date(2022, 12, 20, 12, 43, 5, 123e6, time.FixedZone("", 3600)),
+			Level:    L_Warn,
+			Msg:      "hi",
+			Prefixes: []string{"app", "db"},
+			Fields: M{
+				{"user", "bob"},
+				{"err", errors.New("boom")},
+				{"http", M{{"status", 404}}},
+				{"url.path", "/x"},
+				{"log.level", "x"},
+				{"error", M{{"code", 1}}},
+				{"n", 3},
+				{"err2", errors.New("second")},
+			},
+		}
+		if err := out.Log(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+	line := `{"@timestamp":"2022-12-20T11:43:05.123Z","log.level":"warn","message":"hi","ecs.version":"` + ECSVersion + `",` +
+		`"log.logger":"app.db","error":{"message":"boom","type":"*errors.errorString"},` +
+		`"labels":{"user":"bob","n":"3","err2":"second"},"http":{"status":404},"url.path":"/x"}` + "\n"
+	if w.String() != line+line {
+		t.Errorf("got  %s\nwant %s", w.String(), line+line)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrFieldCollision) {
+		t.Errorf("errors = %v, want one ErrFieldCollision", errs)
+	}
+}
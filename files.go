@@ -6,6 +6,8 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sync"
 	"time"
 )
 
@@ -16,75 +18,300 @@ type FileOutput struct {
 	logLevel   LogLevel
 	outputType OutputType
 	LogFunc    func(*[]byte, *LogEntry, int, io.Writer) error
+
+	// mu guards the file and everything related to it as Log can be called
+	// concurrently by multiple log managers
+	mu      sync.Mutex
+	base    string // path given to the constructor
+	path    string // path of the file being written
+	size    int64
+	maxSize int64
+
+	every   time.Duration
+	next    time.Time
+	symlink string
+
+	compress  bool
+	archive   ArchiveSink
+	retention *RetentionPolicy
+	pattern   string         // glob matching files produced by the output
+	names     *regexp.Regexp // filters files matched by pattern
+	// background work done on rotated files
+	post   sync.Mutex
+	postWg sync.WaitGroup
+	jobs   []func()
+
+	signals []os.Signal
+	stop    chan struct{} // closed by LogClose to stop goroutines
+	closed  bool
+
+	syncEvery    int
+	syncInterval time.Duration
+	syncLevel    *LogLevel
+	unsynced     int
+
+	lock bool
+
+	template string
+	seq      int
+	mkdir    os.FileMode
+
+	perm       os.FileMode
+	noFollow   bool
+	checkOwner bool
+	exclusive  bool
+
+	guard          *DiskGuard
+	lastCheck      time.Time
+	tripped        bool
+	fallbackClosed bool
 }
 
+// FileOption configures a FileOutput before its file is opened
+type FileOption func(*FileOutput)
+
 // NewFileOutput opens or creates a file either appending or truncating it and returns it as an Output.
 // It automatically closes file with 'LogClose()'.
 //
-// date arg specifies wether to add date and time before file name
+// date arg specifies wether to add date and time before file name,
+// it is ignored if a name template is given (see WithNameTemplate)
 //
 // If file is closed in any way other than by output, the output is automatically removed from each log manager
 // it is attached on next log call by such.
 //
 // Output type default to Text.
 //
+// The returned Output is a *FileOutput (ex: to call Reopen).
+//
 // NOTE: if for any reason FileOutput is not added to any logger, it is caller's responsibility to call LogClose once.
-func NewFileOutput(path string, date bool, flags int, logLevel LogLevel, outputType OutputType, append bool) (Output, error) {
-	var osFlags int
-	if append {
-		osFlags = os.O_CREATE | os.O_APPEND | os.O_WRONLY
-	} else {
-		osFlags = os.O_CREATE | os.O_TRUNC | os.O_WRONLY
-	}
-	if date {
-		t := time.Now()
-		dir, name := filepath.Split(path)
-		path = filepath.Join(dir, fmt.Sprintf("%v/%v/%v %v:%v:%v ", t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second())+name)
-	}
-	f, err := os.OpenFile(path, osFlags, 0664)
+func NewFileOutput(path string, date bool, flags int, logLevel LogLevel, outputType OutputType, append bool, opts ...FileOption) (Output, error) {
+	output, err := newFileOutput(path, date, flags, logLevel, outputType, append, opts...)
 	if err != nil {
 		return nil, err
 	}
+	return output, nil
+}
 
+func newFileOutput(path string, date bool, flags int, logLevel LogLevel, outputType OutputType, append bool, opts ...FileOption) (*FileOutput, error) {
+	var osFlags = os.O_CREATE | os.O_TRUNC | os.O_WRONLY
+	if append {
+		osFlags = appendFlags
+	}
 	output := &FileOutput{
-		f:        f,
 		flags:    flags,
 		logLevel: logLevel,
+		base:     path,
+		stop:     make(chan struct{}),
+		perm:     0664,
+	}
+	output.LogFunc, output.outputType = logFunc(outputType)
+	for _, opt := range opts {
+		opt(output)
+	}
+	if output.lock && !lockSupported {
+		return nil, ErrLockUnsupported
+	}
+	if output.guard != nil && !diskGuardSupported {
+		return nil, ErrDiskGuardUnsupported
+	}
+	if (output.noFollow || output.checkOwner) && !secureSupported {
+		return nil, ErrSecureUnsupported
 	}
 
-	switch outputType {
-	case T_Text:
-		output.LogFunc = TextLogFunc
-		output.outputType = T_Text
-	case T_JSON:
-		output.LogFunc = JSONLogFunc
-		output.outputType = T_JSON
+	var now = time.Now()
+	var names string
+	switch {
+	case output.template != "":
+		output.pattern = expandTemplate(path, output.template, now, 0, true)
+		names = templateRegexp(path, output.template)
+	case date:
+		dir, name := filepath.Split(path)
+		output.base = filepath.Join(dir, fmt.Sprintf("%v/%v/%v %v:%v:%v ", now.Year(), now.Month(), now.Day(), now.Hour(), now.Minute(), now.Second())+name)
+		output.pattern = filepath.Join(dir, "*", "*", "* "+name)
+		sep := regexp.QuoteMeta(string(filepath.Separator))
+		names = dirRegexp(path) + `\d+` + sep + `[A-Za-z]+` + sep + `\d+ \d+:\d+:\d+ ` + regexp.QuoteMeta(name)
 	default:
-		output.LogFunc = TextLogFunc
-		output.outputType = T_Text
+		output.pattern = path
+		names = regexp.QuoteMeta(filepath.Clean(path))
+	}
+	if output.every > 0 {
+		now, output.next = period(now, output.every)
+	}
+	output.path = output.newName(now)
+	output.names = output.nameRegexp(names)
+	if output.names.NumSubexp() != 0 {
+		// sequence numbers continue after the ones of existing files
+		if seq := output.lastSeq(); seq >= 0 {
+			output.seq = seq + 1
+			output.path = output.newName(now)
+		}
+	}
+
+	if output.exclusive {
+		// only the first file, files opened again (ex: Reopen) already exist
+		osFlags |= os.O_EXCL
+	}
+	f, err := output.open(output.path, osFlags)
+	if err != nil {
+		return nil, err
+	}
+	output.f = f
+	if info, err := f.Stat(); err == nil {
+		output.size = info.Size()
+	}
+	if output.symlink != "" {
+		if err := output.link(); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	if output.retention != nil {
+		output.background(output.retain)
+	}
+	if len(output.signals) != 0 {
+		output.watchSignals()
+	}
+	if output.syncInterval > 0 {
+		output.syncPeriodically()
 	}
 
 	return output, nil
 }
 
+// flags used to open files after the first one
+const appendFlags = os.O_CREATE | os.O_APPEND | os.O_WRONLY
+
+// open opens a file for output o
+func (o *FileOutput) open(path string, osFlags int) (*os.File, error) {
+	if o.mkdir != 0 {
+		if err := os.MkdirAll(filepath.Dir(path), o.mkdir); err != nil {
+			return nil, err
+		}
+	}
+	if o.noFollow {
+		osFlags |= oNoFollow
+	}
+	f, err := os.OpenFile(path, osFlags, o.perm)
+	if err != nil {
+		if o.noFollow && isSymlink(path) {
+			return nil, fmt.Errorf("opening %v: %w", path, ErrSymlink)
+		}
+		return nil, err
+	}
+	if o.checkOwner {
+		if err := checkOwner(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("opening %v: %w", path, err)
+		}
+	}
+	return f, nil
+}
+
+// replace makes f the file being written and closes the previous one.
+//
+// o.mu must be held.
+func (o *FileOutput) replace(f *os.File) error {
+	old := o.f
+	o.f = f
+	o.size = 0
+	if info, err := f.Stat(); err == nil {
+		o.size = info.Size()
+	}
+	var err error
+	if o.syncs() && o.unsynced > 0 {
+		err = old.Sync()
+	}
+	o.unsynced = 0
+	if e := old.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+// fileWriter is the io.Writer handed to LogFunc, it keeps track of
+// the amount of bytes written to the current file
+type fileWriter FileOutput
+
+func (w *fileWriter) Write(p []byte) (int, error) {
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
 func (o *FileOutput) Log(entry *LogEntry) error {
 	if o.logLevel.Permits(entry.Level) {
-		var e error
-		buf, ok := entry.GetCompiled(o.flags, o.outputType)
-		if !ok {
-			buf = entry.GetBuf()
-			e = o.LogFunc(buf, entry, o.flags, o.f)
-		} else {
-			_, e = o.f.Write(*buf)
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if o.lock {
+			if err := o.lockFile(); err != nil {
+				return err
+			}
+			// o.f changes on rotation
+			defer func() { unlockFile(o.f) }()
+		}
+		var rerr error
+		var t = entry.Time
+		if o.lock && o.every > 0 {
+			// another process may have rolled over and be compressing the file since
+			// entry was created, it must not be written to once its period is over
+			if now := time.Now(); now.After(t) {
+				t = now
+			}
+		}
+		if o.every > 0 && !t.Before(o.next) {
+			// on failure keep on writing to the previous file
+			rerr = o.rollover(t)
+			if o.lock && rerr == nil {
+				if err := o.lockFile(); err != nil {
+					return err
+				}
+			}
 		}
-		if errors.Is(e, ErrOutputClosed) {
-			e = ErrOutputClosed
+		if o.guard != nil {
+			done, err := o.guardDisk(entry)
+			if done {
+				return err
+			}
+			if rerr == nil {
+				rerr = err
+			}
+		}
+		e := o.write(entry)
+		if e == nil {
+			e = rerr
 		}
 		return e
 	}
 	return nil
 }
 
+// write writes entry to the file and rotates it if needed.
+//
+// o.mu and the file lock (if any) must be held.
+func (o *FileOutput) write(entry *LogEntry) error {
+	var e error
+	buf, ok := entry.GetCompiled(o.flags, o.outputType)
+	if !ok {
+		buf = entry.GetBuf()
+		e = o.LogFunc(buf, entry, o.flags, (*fileWriter)(o))
+	} else {
+		_, e = (*fileWriter)(o).Write(*buf)
+	}
+	if errors.Is(e, ErrOutputClosed) {
+		return ErrOutputClosed
+	}
+	// the entry may have been written despite an error returned by LogFunc
+	if err := o.maybeSync(entry.Level); e == nil {
+		e = err
+	}
+	if o.maxSize > 0 && o.size >= o.maxSize {
+		if err := o.rotate(); e == nil {
+			e = err
+		}
+	}
+	return e
+}
+
 func (o *FileOutput) OnAdd() {
 	o.add++
 }
@@ -114,5 +341,21 @@ func (o *FileOutput) LogClose() error {
 		o.add--
 		return nil
 	}
-	return o.f.Close()
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	close(o.stop)
+	var err error
+	if o.syncs() && o.unsynced > 0 {
+		err = o.f.Sync()
+	}
+	if e := o.f.Close(); err == nil {
+		err = e
+	}
+	o.mu.Unlock()
+	o.postWg.Wait()
+	return err
 }
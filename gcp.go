@@ -0,0 +1,200 @@
+package log
+
+import (
+	"encoding/json"
+	"io"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SourceLocation is the location in source code of a log call,
+// written as logging.googleapis.com/sourceLocation by GCP outputs when used as a field value
+//
+// ex: logger.AddFields(M{{"caller", Caller(0)}}).Info("msg")
+type SourceLocation struct {
+	File     string
+	Line     int
+	Function string
+}
+
+// Caller returns the SourceLocation of its caller, skip being the number of
+// additional stack frames to ascend
+func Caller(skip int) SourceLocation {
+	pc, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return SourceLocation{}
+	}
+	var function string
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		function = fn.Name()
+	}
+	return SourceLocation{File: file, Line: line, Function: function}
+}
+
+func (s SourceLocation) MarshalJSON() ([]byte, error) {
+	return json.Marshal(M{{"file", s.File}, {"line", strconv.Itoa(s.Line)}, {"function", s.Function}})
+}
+
+// GCPConfig describes the structured logs written by a LogFunc returned by NewGCPLogFunc
+type GCPConfig struct {
+	// project ID used to write trace IDs in the form projects/ProjectID/traces/TraceID,
+	// trace IDs are written as is if empty
+	ProjectID string
+
+	// key of the field holding the trace ID ("trace" by default)
+	TraceKey string
+
+	// key of the field holding the span ID ("spanId" by default)
+	SpanKey string
+
+	// labels added to every entry
+	Labels map[string]string
+}
+
+const (
+	gcpSourceLocation = "logging.googleapis.com/sourceLocation"
+	gcpTrace          = "logging.googleapis.com/trace"
+	gcpSpanID         = "logging.googleapis.com/spanId"
+	gcpLabels         = "logging.googleapis.com/labels"
+)
+
+// GCPSeverity returns the Google Cloud Logging severity of level
+func GCPSeverity(level LogLevel) string {
+	switch level {
+	case L_Debug:
+		return "DEBUG"
+	case L_Info:
+		return "INFO"
+	case L_Warn:
+		return "WARNING"
+	case L_Error:
+		return "ERROR"
+	case L_Fatal:
+		return "CRITICAL"
+	default:
+		return "DEFAULT"
+	}
+}
+
+// NewGCPLogFunc returns a LogFunc writing entries as JSON objects understood by
+// Google Cloud Logging when written to stdout on GKE or Cloud Run, along with the
+// OutputType it adds buffers with:
+//
+//	{"severity":"ERROR","message":"msg","timestamp":"2022-12-20T12:43:05.123456789Z",
+//	"logging.googleapis.com/labels":{"logger":"app.db"},"key":"value"}
+//
+// Prefixes are joined with '.' into the 'logger' label (or only the last one with F_LastPrefix).
+//
+// Fields are added as top level fields with any F_Fields_* flag, apart from the trace and span fields
+// (see GCPConfig) and the first field holding a SourceLocation that are written in their dedicated
+// fields. Fields with the same key as a key written by the LogFunc are left out (see
+// ErrFieldCollision). Labels are sorted by key.
+func NewGCPLogFunc(cfg GCPConfig) (func(*[]byte, *LogEntry, int, io.Writer) error, OutputType) {
+	setDefault(&cfg.TraceKey, "trace")
+	setDefault(&cfg.SpanKey, "spanId")
+	var g = &gcpFormat{cfg: cfg, outputType: NewOutputType()}
+	// sorted so that entries are always written the same way
+	var keys = make([]string, 0, len(cfg.Labels))
+	for k := range cfg.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		g.labels.Add(k, cfg.Labels[k])
+	}
+	return g.logFunc, g.outputType
+}
+
+// NewGCPOutput returns an Output writing entries as Google Cloud Logging structured logs
+// (see NewGCPLogFunc)
+func NewGCPOutput(w io.Writer, flags int, cfg GCPConfig, close bool) Output {
+	logFunc, outputType := NewGCPLogFunc(cfg)
+	return &outputWrapper{
+		w:          w,
+		close:      close,
+		flags:      flags,
+		logLevel:   L_Info,
+		outputType: outputType,
+		LogFunc:    logFunc,
+		CloseFunc:  DefaultCloseFunc,
+	}
+}
+
+type gcpFormat struct {
+	cfg        GCPConfig
+	labels     M
+	collided   sync.Once
+	outputType OutputType
+}
+
+func (g *gcpFormat) logFunc(buf *[]byte, entry *LogEntry, flags int, w io.Writer) error {
+	var cfg, outputType = &g.cfg, g.outputType
+	var m = M{}
+	if flags&F_Level != 0 {
+		m.Add("severity", GCPSeverity(entry.Level))
+	}
+	m.Add("message", entry.Msg)
+	if flags&F_Time != 0 {
+		m.Add("timestamp", entry.Time.UTC().Format(time.RFC3339Nano))
+	}
+
+	var labels = append(M{}, g.labels...)
+	if len(entry.Prefixes) != 0 && flags&(F_Prefix|F_LastPrefix) != 0 {
+		if flags&F_LastPrefix != 0 {
+			labels.Add("logger", entry.Prefixes[len(entry.Prefixes)-1])
+		} else {
+			labels.Add("logger", strings.Join(entry.Prefixes, "."))
+		}
+	}
+	if len(labels) != 0 {
+		m.Add(gcpLabels, labels)
+	}
+
+	if len(entry.Fields) != 0 && flags&(F_Fields|F_Fields_A|F_Fields_B) != 0 {
+		var hasSource bool
+		for _, v := range entry.Fields {
+			switch {
+			case v.Key == cfg.TraceKey:
+				trace := fieldString(v.Val)
+				if cfg.ProjectID != "" && !strings.HasPrefix(trace, "projects/") {
+					trace = "projects/" + cfg.ProjectID + "/traces/" + trace
+				}
+				m.Add(gcpTrace, trace)
+			case v.Key == cfg.SpanKey:
+				m.Add(gcpSpanID, fieldString(v.Val))
+			case !hasSource && isSourceLocation(v.Val):
+				hasSource = true
+				m.Add(gcpSourceLocation, v.Val)
+			case v.Key == "severity" || v.Key == "message" || v.Key == "timestamp" || strings.HasPrefix(v.Key, "logging.googleapis.com/"):
+				reportCollision(&g.collided, v.Key)
+			default:
+				m.AddE(v)
+			}
+		}
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		entry.AddCompiled(F_NotSave, outputType, buf)
+		return err
+	}
+	if flags&F_NewLine != 0 {
+		data = append(data, '\n')
+	}
+	entry.AddCompiled(flags, outputType, &data)
+	entry.AddCompiled(F_NotSave, outputType, buf)
+	_, err = w.Write(data)
+	return err
+}
+
+func isSourceLocation(v interface{}) bool {
+	switch v.(type) {
+	case SourceLocation, *SourceLocation:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestGCPLogFunc(t *testing.T) {
+	defer func(h func(error)) { ErrorHandler = h }(ErrorHandler)
+	ErrorHandler = func(error) {}
+
+	cfg := GCPConfig{
+		ProjectID: "proj",
+		Labels:    map[string]string{"env": "prod", "zone": "b", "app": "api", "team": "core"},
+	}
+	want := `{"severity":"WARNING","message":"careful","timestamp":"2022-12-20T12:43:05.5Z",` +
+		`"logging.googleapis.com/labels":{"app":"api","env":"prod","team":"core","zone":"b","logger":"app.db"},` +
+		`"logging.googleapis.com/sourceLocation":{"file":"main.go","line":"12","function":"main.main"},` +
+		`"logging.googleapis.com/trace":"projects/proj/traces/abc","logging.googleapis.com/spanId":"7","n":3}` + "\n"
+	for i := 0; i < 20; i++ {
+		fn, _ := NewGCPLogFunc(cfg)
+		entry := &LogEntry{
+			Time:     time.Date(2022, 12, 20, 12, 43, 5, 5e8, time.UTC),
+			Level:    L_Warn,
+			Msg:      "careful",
+			Prefixes: []string{"app", "db"},
+			Fields: M{
+				{"caller", SourceLocation{File: "main.go", Line: 12, Function: "main.main"}},
+				{"trace", "abc"},
+				{"spanId", 7},
+				{"n", 3},
+				{"severity", "ignored"},
+			},
+		}
+		var w bytes.Buffer
+		if err := fn(entry.GetBuf(), entry, F_Std, &w); err != nil {
+			t.Fatal(err)
+		}
+		if w.String() != want {
+			t.Fatalf("got  %s\nwant %s", w.String(), want)
+		}
+	}
+}
+
+func TestGCPSeverity(t *testing.T) {
+	for level, want := range map[LogLevel]string{L_Debug: "DEBUG", L_Info: "INFO", L_Warn: "WARNING", L_Error: "ERROR", L_Fatal: "CRITICAL"} {
+		if got := GCPSeverity(level); got != want {
+			t.Errorf("GCPSeverity(%v) = %v, want %v", level, got, want)
+		}
+	}
+}
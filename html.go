@@ -0,0 +1,194 @@
+package log
+
+import (
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// htmlOutput is an outputWrapper writing rows of an HTML document
+// that is finalized on close
+type htmlOutput struct {
+	*outputWrapper
+	mu     sync.Mutex
+	closed bool
+}
+
+var htmlOutputType = NewOutputType()
+
+// NewHTMLOutput returns an Output writing a self-contained HTML report to w: a table of entries
+// coloured after their level, with fields folded in a collapsible cell (with any F_Fields_* flag)
+// and filters by level and prefix run by the browser.
+//
+// The beginning of the document is written to w before returning and 'LogClose()' ends it,
+// before closing w if close is true. Entries logged after that return ErrOutputClosed.
+func NewHTMLOutput(w io.Writer, flags int, title string, close bool) (Output, error) {
+	title = html.EscapeString(title)
+	if _, err := io.WriteString(w, strings.Replace(htmlHeader, "{title}", title, 2)); err != nil {
+		return nil, err
+	}
+	o := &htmlOutput{
+		outputWrapper: &outputWrapper{
+			w:          w,
+			close:      close,
+			flags:      flags,
+			logLevel:   L_Info,
+			outputType: htmlOutputType,
+			LogFunc:    HTMLLogFunc,
+		},
+	}
+	o.CloseFunc = func(w io.Writer, close bool) error {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if o.closed {
+			return nil
+		}
+		o.closed = true
+		_, err := io.WriteString(w, htmlFooter)
+		if e := DefaultCloseFunc(w, close); err == nil {
+			err = e
+		}
+		return err
+	}
+	return o, nil
+}
+
+func (o *htmlOutput) Log(entry *LogEntry) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrOutputClosed
+	}
+	return o.outputWrapper.Log(entry)
+}
+
+// HTMLLogFunc formats entry with specified flags and writes it to w as a row of the
+// table of an HTML report (see NewHTMLOutput)
+func HTMLLogFunc(buf *[]byte, entry *LogEntry, flags int, w io.Writer) error {
+	var level = entry.Level.String()
+	var prefix string
+	if len(entry.Prefixes) != 0 && flags&(F_Prefix|F_LastPrefix) != 0 {
+		if flags&F_LastPrefix != 0 {
+			prefix = entry.Prefixes[len(entry.Prefixes)-1]
+		} else {
+			prefix = strings.Join(entry.Prefixes, ".")
+		}
+	}
+	prefix = html.EscapeString(prefix)
+
+	*buf = append(*buf, `<tr class="`...)
+	*buf = append(*buf, strings.ToLower(level)...)
+	*buf = append(*buf, `" data-level="`...)
+	*buf = append(*buf, level...)
+	*buf = append(*buf, `" data-prefix="`...)
+	*buf = append(*buf, prefix...)
+	*buf = append(*buf, `"><td>`...)
+	if flags&(F_Time|F_Micro) != 0 {
+		if flags&F_Micro != 0 {
+			*buf = entry.Time.AppendFormat(*buf, "2006-01-02 15:04:05.000000")
+		} else {
+			*buf = entry.Time.AppendFormat(*buf, "2006-01-02 15:04:05")
+		}
+	}
+	*buf = append(*buf, "</td><td>"...)
+	if flags&F_Level != 0 {
+		*buf = append(*buf, level...)
+	}
+	*buf = append(*buf, "</td><td>"...)
+	*buf = append(*buf, prefix...)
+	*buf = append(*buf, "</td><td>"...)
+	*buf = append(*buf, html.EscapeString(strings.TrimSuffix(entry.Msg, "\n"))...)
+	*buf = append(*buf, "</td><td>"...)
+	if len(entry.Fields) != 0 && flags&(F_Fields|F_Fields_A|F_Fields_B) != 0 {
+		*buf = append(*buf, "<details><summary>"...)
+		*buf = strconv.AppendInt(*buf, int64(len(entry.Fields)), 10)
+		if len(entry.Fields) == 1 {
+			*buf = append(*buf, " field"...)
+		} else {
+			*buf = append(*buf, " fields"...)
+		}
+		*buf = append(*buf, "</summary><dl>"...)
+		for _, v := range entry.Fields {
+			*buf = append(*buf, "<dt>"...)
+			*buf = append(*buf, html.EscapeString(v.Key)...)
+			*buf = append(*buf, "</dt><dd>"...)
+			*buf = append(*buf, html.EscapeString(fieldString(v.Val))...)
+			*buf = append(*buf, "</dd>"...)
+		}
+		*buf = append(*buf, "</dl></details>"...)
+	}
+	*buf = append(*buf, "</td></tr>\n"...)
+
+	entry.AddCompiled(flags, htmlOutputType, buf)
+	_, err := w.Write(*buf)
+	return err
+}
+
+const htmlHeader = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{title}</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+table.log { border-collapse: collapse; width: 100%; font-size: 13px; }
+table.log th, table.log td { border-bottom: 1px solid #ddd; padding: 2px 6px; text-align: left; vertical-align: top; }
+table.log th { background: #f4f4f4; position: sticky; top: 0; }
+table.log td:nth-child(4) { white-space: pre-wrap; font-family: monospace; }
+table.log td:nth-child(1) { white-space: nowrap; }
+dl { margin: 2px 0; display: grid; grid-template-columns: auto 1fr; gap: 0 8px; }
+dt { font-weight: bold; }
+dd { margin: 0; white-space: pre-wrap; font-family: monospace; }
+tr.debug { color: #777; }
+tr.warn { background: #fff8e1; }
+tr.error { background: #fdecea; }
+tr.fatal { background: #f8c9c4; font-weight: bold; }
+.filters { margin-bottom: 1em; }
+</style>
+<script>
+document.addEventListener("DOMContentLoaded", function () {
+	var level = document.getElementById("level"), prefix = document.getElementById("prefix");
+	var rows = document.querySelectorAll("table.log tbody tr");
+	var prefixes = {};
+	rows.forEach(function (r) {
+		var p = r.dataset.prefix;
+		if (p && !prefixes[p]) {
+			prefixes[p] = true;
+			var o = document.createElement("option");
+			o.textContent = p;
+			prefix.appendChild(o);
+		}
+	});
+	var levels = ["DEBUG", "INFO", "WARN", "ERROR", "FATAL"];
+	function filter() {
+		var min = levels.indexOf(level.value);
+		rows.forEach(function (r) {
+			var p = r.dataset.prefix;
+			var ok = levels.indexOf(r.dataset.level) >= min &&
+				(prefix.value === "" || p === prefix.value || p.indexOf(prefix.value + ".") === 0);
+			r.style.display = ok ? "" : "none";
+		});
+	}
+	level.addEventListener("change", filter);
+	prefix.addEventListener("change", filter);
+});
+</script>
+</head>
+<body>
+<h1>{title}</h1>
+<div class="filters">
+<label>Level <select id="level"><option>DEBUG</option><option>INFO</option><option>WARN</option><option>ERROR</option><option>FATAL</option></select></label>
+<label>Prefix <select id="prefix"><option value="">all</option></select></label>
+</div>
+<table class="log">
+<thead><tr><th>Time</th><th>Level</th><th>Prefix</th><th>Message</th><th>Fields</th></tr></thead>
+<tbody>
+`
+
+const htmlFooter = `</tbody>
+</table>
+</body>
+</html>
+`
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// closeCounter is a buffer counting its Close calls
+type closeCounter struct {
+	bytes.Buffer
+	closes int
+}
+
+func (c *closeCounter) Close() error {
+	c.closes++
+	return nil
+}
+
+func TestHTMLOutput(t *testing.T) {
+	var w closeCounter
+	out, err := NewHTMLOutput(&w, F_Level|F_Prefix|F_Fields, "<a&b>", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(w.String(), "<!DOCTYPE html>") || strings.Count(w.String(), "&lt;a&amp;b&gt;") != 2 {
+		t.Errorf("header = %q, want the escaped title twice", w.String())
+	}
+	entry := &LogEntry{
+		Level:    L_Warn,
+		Msg:      "<script>x</script>\n",
+		Prefixes: []string{"<p>", "db"},
+		Fields:   M{{"<k>", `"v"&`}},
+	}
+	if err := out.Log(entry); err != nil {
+		t.Fatal(err)
+	}
+	want := `<tr class="warn" data-level="WARN" data-prefix="&lt;p&gt;.db"><td></td><td>WARN</td><td>&lt;p&gt;.db</td>` +
+		`<td>&lt;script&gt;x&lt;/script&gt;</td><td><details><summary>1 field</summary>` +
+		`<dl><dt>&lt;k&gt;</dt><dd>&#34;v&#34;&amp;</dd></dl></details></td></tr>` + "\n"
+	if !strings.HasSuffix(w.String(), want) {
+		t.Errorf("row:\ngot  %q\nwant %q", w.String()[strings.LastIndex(w.String(), "<tr"):], want)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := out.LogClose(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !strings.HasSuffix(w.String(), want+htmlFooter) || strings.Count(w.String(), "</html>") != 1 {
+		t.Errorf("document does not end with a single footer: %q", w.String())
+	}
+	if w.closes != 1 {
+		t.Errorf("writer closed %v times, want 1", w.closes)
+	}
+	if err := out.Log(entry); err != ErrOutputClosed {
+		t.Errorf("Log after close = %v, want %v", err, ErrOutputClosed)
+	}
+}
@@ -0,0 +1,210 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// ErrFieldCollision is reported to ErrorHandler by LogFuncs writing fields next to their own
+// keys (see NewJSONLogFunc, NewGCPLogFunc, ECSLogFunc and NewCEFLogFunc) when a field of an
+// entry has the same key as a key used by the output, only for the first such field of each
+// LogFunc. Such fields are left out of the entry, which is still written.
+var ErrFieldCollision = fmt.Errorf("field collides with a reserved key")
+
+// reportCollision reports the first field collision of a LogFunc
+func reportCollision(once *sync.Once, key string) {
+	once.Do(func() {
+		reportError(fmt.Errorf("%w: %q (further collisions are not reported)", ErrFieldCollision, key))
+	})
+}
+
+type JSONTimeEncoding int
+
+const (
+	// time marshaled by json.Marshal (RFC 3339 with nanoseconds)
+	JSONTimeRFC3339Nano JSONTimeEncoding = iota
+	// milliseconds since unix epoch
+	JSONTimeMillis
+	// nanoseconds since unix epoch
+	JSONTimeNanos
+)
+
+type JSONLevelEncoding int
+
+const (
+	// LogLevel.String() (ex: "INFO")
+	JSONLevelName JSONLevelEncoding = iota
+	// lowercase LogLevel.String() (ex: "info")
+	JSONLevelLower
+	// LogLevel as a number (ex: 1)
+	JSONLevelNumber
+)
+
+type JSONPrefixEncoding int
+
+const (
+	// array of prefixes
+	JSONPrefixArray JSONPrefixEncoding = iota
+	// prefixes joined with JSONConfig.PrefixSep
+	JSONPrefixJoined
+	// last prefix only, same as F_LastPrefix
+	JSONPrefixLast
+)
+
+// JSONConfig describes the JSON objects written by a LogFunc returned by NewJSONLogFunc.
+//
+// Empty keys default to the keys used by JSONLogFunc (see TimeFieldKey) and
+// the zero value of encodings is the encoding used by JSONLogFunc.
+type JSONConfig struct {
+	TimeKey   string
+	LevelKey  string
+	PrefixKey string
+	MsgKey    string
+
+	// key of the object or array holding fields with F_Fields_A or F_Fields_B
+	FieldsKey string
+
+	TimeEncoding   JSONTimeEncoding
+	LevelEncoding  JSONLevelEncoding
+	PrefixEncoding JSONPrefixEncoding
+
+	// separator used by JSONPrefixJoined ('.' by default)
+	PrefixSep string
+}
+
+// NewJSONLogFunc returns a LogFunc similar to JSONLogFunc but writing objects described
+// by cfg, along with the OutputType it adds buffers with.
+//
+// An error is returned if two keys of cfg are the same.
+//
+// Unlike JSONLogFunc, fields added as top level fields (F_Fields) with the same key as
+// a key written by the LogFunc are left out (see ErrFieldCollision).
+func NewJSONLogFunc(cfg JSONConfig) (func(*[]byte, *LogEntry, int, io.Writer) error, OutputType, error) {
+	setDefault(&cfg.TimeKey, TimeFieldKey)
+	setDefault(&cfg.LevelKey, LevelFieldKey)
+	setDefault(&cfg.PrefixKey, PrefixFieldKey)
+	setDefault(&cfg.MsgKey, MsgFieldKey)
+	setDefault(&cfg.FieldsKey, FieldsFieldKey)
+	setDefault(&cfg.PrefixSep, ".")
+
+	var keys = []string{cfg.TimeKey, cfg.LevelKey, cfg.PrefixKey, cfg.MsgKey, cfg.FieldsKey}
+	for i, k := range keys {
+		for _, v := range keys[i+1:] {
+			if k == v {
+				return nil, 0, fmt.Errorf("json config: key %q is used more than once", k)
+			}
+		}
+	}
+
+	outputType := NewOutputType()
+	var collided sync.Once
+	return func(buf *[]byte, entry *LogEntry, flags int, w io.Writer) error {
+		return jsonLogFunc(buf, entry, flags, w, &cfg, &collided, outputType)
+	}, outputType, nil
+}
+
+// same as NewJSONOutput but writes objects described by cfg (see NewJSONLogFunc)
+func NewJSONOutputWithConfig(w io.Writer, flags int, cfg JSONConfig, close bool) (Output, error) {
+	logFunc, outputType, err := NewJSONLogFunc(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &outputWrapper{
+		w:          w,
+		close:      close,
+		flags:      flags,
+		logLevel:   L_Info,
+		outputType: outputType,
+		LogFunc:    logFunc,
+		CloseFunc:  DefaultCloseFunc,
+	}, nil
+}
+
+func setDefault(s *string, v string) {
+	if *s == "" {
+		*s = v
+	}
+}
+
+// jsonLogFunc is the implementation of JSONLogFunc if cfg is nil and
+// of LogFuncs returned by NewJSONLogFunc otherwise
+func jsonLogFunc(buf *[]byte, entry *LogEntry, flags int, w io.Writer, cfg *JSONConfig, collided *sync.Once, outputType OutputType) error {
+	var legacy = cfg == nil
+	if legacy {
+		cfg = &JSONConfig{
+			TimeKey:   TimeFieldKey,
+			LevelKey:  LevelFieldKey,
+			PrefixKey: PrefixFieldKey,
+			MsgKey:    MsgFieldKey,
+			FieldsKey: FieldsFieldKey,
+		}
+	}
+
+	var m = M{}
+	if flags&F_Time != 0 {
+		switch cfg.TimeEncoding {
+		case JSONTimeMillis:
+			m.Add(cfg.TimeKey, entry.Time.UnixMilli())
+		case JSONTimeNanos:
+			m.Add(cfg.TimeKey, entry.Time.UnixNano())
+		default:
+			m.Add(cfg.TimeKey, entry.Time)
+		}
+	}
+
+	if len(entry.Prefixes) != 0 && flags&(F_Prefix|F_LastPrefix) != 0 {
+		switch {
+		case flags&F_LastPrefix != 0 || cfg.PrefixEncoding == JSONPrefixLast:
+			m.Add(cfg.PrefixKey, entry.Prefixes[len(entry.Prefixes)-1])
+		case cfg.PrefixEncoding == JSONPrefixJoined:
+			m.Add(cfg.PrefixKey, strings.Join(entry.Prefixes, cfg.PrefixSep))
+		default:
+			m.Add(cfg.PrefixKey, append([]string{}, entry.Prefixes...))
+		}
+	}
+
+	if flags&F_Level != 0 {
+		switch cfg.LevelEncoding {
+		case JSONLevelLower:
+			m.Add(cfg.LevelKey, strings.ToLower(entry.Level.String()))
+		case JSONLevelNumber:
+			m.Add(cfg.LevelKey, int(entry.Level))
+		default:
+			m.Add(cfg.LevelKey, entry.Level.String())
+		}
+	}
+
+	if len(entry.Fields) != 0 && flags&(F_Fields|F_Fields_A|F_Fields_B) != 0 {
+		if flags&F_Fields_A != 0 {
+			m.Add(cfg.FieldsKey, entry.Fields)
+		} else if flags&F_Fields != 0 {
+			if legacy {
+				m.AddE(entry.Fields...)
+			} else {
+				for _, v := range entry.Fields {
+					if v.Key == cfg.TimeKey || v.Key == cfg.LevelKey || v.Key == cfg.PrefixKey || v.Key == cfg.MsgKey {
+						reportCollision(collided, v.Key)
+						continue
+					}
+					m.AddE(v)
+				}
+			}
+		} else if flags&F_Fields_B != 0 {
+			m.Add(cfg.FieldsKey, entry.Fields.AsArray())
+		}
+	}
+
+	m.Add(cfg.MsgKey, entry.Msg)
+
+	data, _ := json.Marshal(m)
+	if flags&F_NewLine != 0 {
+		data = append(data, '\n')
+	}
+	entry.AddCompiled(flags, outputType, &data)
+	entry.AddCompiled(F_NotSave, outputType, buf)
+	_, err := w.Write(data)
+	return err
+}
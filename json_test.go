@@ -0,0 +1,65 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestJSONConfig(t *testing.T) {
+	fn, _, err := NewJSONLogFunc(JSONConfig{
+		TimeKey:        "ts",
+		LevelKey:       "severity",
+		MsgKey:         "message",
+		LevelEncoding:  JSONLevelLower,
+		PrefixEncoding: JSONPrefixJoined,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var w bytes.Buffer
+	entry := &LogEntry{Level: L_Warn, Msg: "hi", Prefixes: []string{"app", "db"}, Fields: M{{"k", 1}}}
+	if err := fn(entry.GetBuf(), entry, F_Level|F_Prefix|F_Fields|F_NewLine, &w); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"prefix":"app.db","severity":"warn","k":1,"message":"hi"}` + "\n"; w.String() != want {
+		t.Errorf("got %q, want %q", w.String(), want)
+	}
+
+	if _, _, err := NewJSONLogFunc(JSONConfig{TimeKey: "msg"}); err == nil {
+		t.Error("expected an error for a key used twice")
+	}
+}
+
+func TestJSONCollisionRotates(t *testing.T) {
+	var errs []error
+	defer func(h func(error)) { ErrorHandler = h }(ErrorHandler)
+	ErrorHandler = func(err error) { errs = append(errs, err) }
+
+	dir := t.TempDir()
+	out, err := NewRotatingFileOutput(filepath.Join(dir, "app.log"), 100, F_Level|F_Fields|F_NewLine, L_Info, T_JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := out.(*FileOutput)
+	f.LogFunc, f.outputType, err = NewJSONLogFunc(JSONConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		entry := &LogEntry{Level: L_Info, Msg: "some message", Fields: M{{"level", "x"}}}
+		if err := out.Log(entry); err != nil {
+			t.Fatalf("Log = %v", err)
+		}
+	}
+	if err := out.LogClose(); err != nil {
+		t.Fatal(err)
+	}
+	if files := listDir(t, dir); len(files) < 5 {
+		t.Errorf("files = %q, want the file to be rotated", files)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], ErrFieldCollision) {
+		t.Errorf("reported errors = %v, want a single collision", errs)
+	}
+}
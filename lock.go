@@ -0,0 +1,90 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// ErrLockUnsupported is returned by NewFileOutput when WithLock is used
+// on a platform where file locking is not supported
+var ErrLockUnsupported = fmt.Errorf("file locking is not supported on this platform")
+
+// WithLock makes FileOutput hold an advisory lock (flock) on its file while
+// writing an entry and while rotating it, so that multiple processes can
+// share the same file, as well as its rotation, without interleaving entries.
+//
+// All processes sharing the file must use WithLock and the same rotation options.
+// Work done on rotated files (see WithCompress, WithArchive and WithRetention) is only
+// done by the first process to claim them, using a '.claim' file next to them.
+//
+// It is only supported on Linux.
+func WithLock() FileOption {
+	return func(o *FileOutput) {
+		o.lock = true
+	}
+}
+
+// lockFile locks the file being written, reopening its path first if
+// another process rotated it, and updates o.size with the actual size of the file.
+//
+// o.mu must be held.
+func (o *FileOutput) lockFile() error {
+	for {
+		if err := lockFile(o.f); err != nil {
+			return err
+		}
+		info, err := o.f.Stat()
+		if err != nil {
+			unlockFile(o.f)
+			return err
+		}
+		current, err := os.Stat(o.path)
+		if err == nil && os.SameFile(info, current) {
+			o.size = info.Size()
+			return nil
+		}
+
+		// the file has been renamed or removed while waiting for the lock
+		unlockFile(o.f)
+		if os.IsNotExist(err) && o.every > 0 {
+			// another process rolled over and compressed or archived the file, it
+			// must not be created again unless its period is not over
+			path := o.path
+			if err := o.rollover(time.Now()); err != nil {
+				return err
+			}
+			if o.path != path {
+				continue
+			}
+		}
+		f, err := o.open(o.path, appendFlags)
+		if err != nil {
+			return err
+		}
+		o.replace(f)
+	}
+}
+
+// claims older than this are left by processes that did not release them
+const staleClaim = 10 * time.Minute
+
+// claim makes sure that work on path is done by a single process (ex: compressing a file
+// every process sharing it rolled over from) by creating path.claim exclusively,
+// release must be called once done.
+func claim(path string) (release func(), ok bool) {
+	name := path + ".claim"
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0664)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(name) }, true
+		}
+		info, err := os.Stat(name)
+		if err != nil || time.Since(info.ModTime()) < staleClaim {
+			return nil, false
+		}
+		os.Remove(name)
+	}
+	return nil, false
+}
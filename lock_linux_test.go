@@ -0,0 +1,98 @@
+package log
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockSharedTimedCompress(t *testing.T) {
+	var mu sync.Mutex
+	var errs []error
+	defer func(h func(error)) { ErrorHandler = h }(ErrorHandler)
+	ErrorHandler = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	const writers = 4
+	var outputs []Output
+	for i := 0; i < writers; i++ {
+		out, err := NewTimedFileOutput(path, time.Second, F_NewLine, L_Info, T_Text, WithLock(), WithCompress(), WithSymlink(""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, out)
+	}
+
+	var wg sync.WaitGroup
+	var written = make([]int, writers)
+	deadline := time.Now().Add(2500 * time.Millisecond)
+	for i, out := range outputs {
+		wg.Add(1)
+		go func(i int, out Output) {
+			defer wg.Done()
+			l := NewLogger().Sync()
+			l.AddOutput(out)
+			for time.Now().Before(deadline) {
+				l.Info("entry")
+				written[i]++
+			}
+			l.Close()
+		}(i, out)
+	}
+	wg.Wait()
+
+	if len(errs) != 0 {
+		t.Fatalf("errors: %v", errs)
+	}
+	var want int
+	for _, n := range written {
+		want += n
+	}
+	var lines int
+	var plain int
+	for _, name := range listDir(t, dir) {
+		var r io.Reader
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		r = f
+		switch {
+		case strings.HasSuffix(name, ".log.gz"):
+			zr, err := gzip.NewReader(f)
+			if err != nil {
+				t.Fatalf("%v: %v", name, err)
+			}
+			r = zr
+		case strings.HasSuffix(name, ".log"):
+			plain++
+		default:
+			t.Fatalf("unexpected file %v", name)
+		}
+		s := bufio.NewScanner(r)
+		for s.Scan() {
+			lines++
+		}
+		if err := s.Err(); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+	}
+	if plain != 1 {
+		t.Errorf("%v uncompressed files, want 1: %q", plain, listDir(t, dir))
+	}
+	if lines != want {
+		t.Errorf("%v lines, want %v", lines, want)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package log
+
+import "os"
+
+const lockSupported = false
+
+func lockFile(f *os.File) error {
+	return ErrLockUnsupported
+}
+
+func unlockFile(f *os.File) error {
+	return ErrLockUnsupported
+}
@@ -0,0 +1,104 @@
+package log
+
+import (
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LogfmtLogFunc uses buf to format entry with specified flags and writes it to w as
+// a logfmt line (ex: 'time=2022-12-20T12:43:05+01:00 level=INFO prefix=app.db msg="some message" key=value')
+// adding buf to entry with Logfmt output type.
+//
+// Prefixes are joined with '.', F_Fields_* flags all add fields as top level pairs
+// and values are quoted only when needed.
+func LogfmtLogFunc(buf *[]byte, entry *LogEntry, flags int, w io.Writer) error {
+	if flags&(F_Time|F_Micro) != 0 {
+		*buf = append(*buf, "time="...)
+		if flags&F_Micro != 0 {
+			*buf = entry.Time.AppendFormat(*buf, "2006-01-02T15:04:05.000000Z07:00")
+		} else {
+			*buf = entry.Time.AppendFormat(*buf, time.RFC3339)
+		}
+		*buf = append(*buf, ' ')
+	}
+
+	if flags&F_Level != 0 {
+		*buf = append(*buf, "level="...)
+		*buf = append(*buf, entry.Level.String()...)
+		*buf = append(*buf, ' ')
+	}
+
+	if len(entry.Prefixes) != 0 && flags&(F_Prefix|F_LastPrefix) != 0 {
+		*buf = append(*buf, "prefix="...)
+		if flags&F_LastPrefix != 0 {
+			appendQuoted(buf, entry.Prefixes[len(entry.Prefixes)-1], "")
+		} else {
+			var start = len(*buf)
+			for i, v := range entry.Prefixes {
+				if i != 0 {
+					*buf = append(*buf, '.')
+				}
+				*buf = append(*buf, v...)
+			}
+			if prefix := string((*buf)[start:]); needsQuote(prefix, "") {
+				*buf = appendQuotedAt(*buf, start, prefix)
+			}
+		}
+		*buf = append(*buf, ' ')
+	}
+
+	*buf = append(*buf, "msg="...)
+	appendQuoted(buf, strings.TrimSuffix(entry.Msg, "\n"), "")
+
+	if flags&(F_Fields|F_Fields_A|F_Fields_B) != 0 {
+		for _, v := range entry.Fields {
+			*buf = append(*buf, ' ')
+			appendLogfmtKey(buf, v.Key)
+			*buf = append(*buf, '=')
+			appendQuoted(buf, fieldString(v.Val), "")
+		}
+	}
+
+	if flags&F_NewLine != 0 {
+		*buf = append(*buf, '\n')
+	}
+
+	entry.AddCompiled(flags, T_Logfmt, buf)
+	_, err := w.Write(*buf)
+	return err
+}
+
+// appendQuotedAt replaces buf[start:] by s quoted
+func appendQuotedAt(buf []byte, start int, s string) []byte {
+	buf = buf[:start]
+	appendQuoted(&buf, s, "")
+	return buf
+}
+
+// appendLogfmtKey appends key replacing characters not allowed in logfmt keys by '_'
+func appendLogfmtKey(buf *[]byte, key string) {
+	if key == "" {
+		*buf = append(*buf, '_')
+		return
+	}
+	for _, r := range key {
+		if r <= ' ' || r == '"' || r == '=' || r == 0x7f {
+			r = '_'
+		}
+		*buf = utf8.AppendRune(*buf, r)
+	}
+}
+
+func NewLogfmtOutput(w io.Writer, flags int, close bool) Output {
+	return &outputWrapper{
+		w:          w,
+		close:      close,
+		flags:      flags,
+		logLevel:   L_Info,
+		outputType: T_Logfmt,
+		LogFunc:    LogfmtLogFunc,
+		CloseFunc:  DefaultCloseFunc,
+	}
+}
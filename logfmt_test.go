@@ -0,0 +1,32 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestLogfmt(t *testing.T) {
+	at := time.Date(2022, 12, 20, 12, 43, 5, 0, time.FixedZone("", 3600))
+	for _, tt := range []struct {
+		flags int
+		entry *LogEntry
+		want  string
+	}{
+		{F_Std, &LogEntry{Time: at, Level: L_Info, Msg: "done\n", Prefixes: []string{"app", "db"}, Fields: M{{"k", "v"}}},
+			"time=2022-12-20T12:43:05+01:00 level=INFO prefix=app.db msg=done k=v\n"},
+		{F_LastPrefix | F_Fields_B | F_NewLine, &LogEntry{Level: L_Warn, Msg: `say "hi"`, Prefixes: []string{"app", "my db"},
+			Fields: M{{"a b", "x=y"}, {"k=\"", "line\nbreak"}, {"", ""}, {"n", 3}}},
+			`prefix="my db" msg="say \"hi\"" a_b="x=y" k__="line\nbreak" _="" n=3` + "\n"},
+		{F_Prefix, &LogEntry{Msg: "", Prefixes: []string{"my", "app"}}, `prefix=my.app msg=""`},
+		{F_Prefix, &LogEntry{Msg: "a\n\n", Prefixes: []string{"my app", "db"}}, `prefix="my app.db" msg="a\n"`},
+	} {
+		var w bytes.Buffer
+		if err := LogfmtLogFunc(tt.entry.GetBuf(), tt.entry, tt.flags, &w); err != nil {
+			t.Fatal(err)
+		}
+		if w.String() != tt.want {
+			t.Errorf("got  %q\nwant %q", w.String(), tt.want)
+		}
+	}
+}
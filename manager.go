@@ -2,6 +2,7 @@ package log
 
 import (
 	"fmt"
+	"os"
 	"sync"
 	"sync/atomic"
 )
@@ -17,6 +18,23 @@ var ErrOutputClosed = fmt.Errorf("output closed")
 
 var ErrManagerClosed = fmt.Errorf("log manager is closed")
 
+// ErrorHandler is called with errors that can not be returned to any caller,
+// such as errors of work outputs do in the background (ex: compressing rotated files).
+//
+// By default errors are printed to os.Stderr.
+//
+// NOTE: ErrorHandler can be called from multiple goroutines and should
+// not be changed once loggers are in use
+var ErrorHandler = func(err error) {
+	fmt.Fprintf(os.Stderr, "log: %v\n", err)
+}
+
+func reportError(err error) {
+	if ErrorHandler != nil {
+		ErrorHandler(err)
+	}
+}
+
 type manager struct {
 	outputs []Output
 	mu      sync.Mutex
@@ -0,0 +1,189 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// ErrMmapUnsupported is returned by NewMmapFileOutput on platforms
+// where memory-mapped files are not supported
+var ErrMmapUnsupported = fmt.Errorf("memory-mapped files are not supported on this platform")
+
+// default amount of bytes a MmapFileOutput grows by
+const defaultChunk = 4 << 20
+
+// MmapFileOutput is a file Output writing entries to a memory-mapped
+// region of its file instead of issuing a write syscall per entry.
+type MmapFileOutput struct {
+	add        int
+	f          *os.File
+	flags      int
+	logLevel   LogLevel
+	outputType OutputType
+	LogFunc    func(*[]byte, *LogEntry, int, io.Writer) error
+
+	mu     sync.Mutex
+	data   []byte // mapped region, the file is at least as long as data
+	size   int    // length of the written part of data
+	chunk  int
+	closed bool
+}
+
+// NewMmapFileOutput opens or creates a file either appending or truncating it
+// and returns it as an Output writing to a memory-mapped region of the file.
+//
+// The file is grown by chunk bytes (4MiB if chunk <= 0) each time the region
+// is full and is truncated to the length actually written with 'LogClose()'.
+// Until then the file holds trailing zeros, so a file left by a program that
+// did not close its output should be stripped of them before being appended to.
+//
+// Output type default to Text.
+//
+// NOTE: truncating the file while it is used by the output results in a SIGBUS.
+// Only supported on Linux.
+func NewMmapFileOutput(path string, chunk int, flags int, logLevel LogLevel, outputType OutputType, append bool) (Output, error) {
+	if !mmapSupported {
+		return nil, ErrMmapUnsupported
+	}
+	if chunk <= 0 {
+		chunk = defaultChunk
+	}
+	var osFlags = os.O_CREATE | os.O_TRUNC | os.O_RDWR
+	if append {
+		osFlags = os.O_CREATE | os.O_RDWR
+	}
+	f, err := os.OpenFile(path, osFlags, 0664)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	output := &MmapFileOutput{
+		f:        f,
+		flags:    flags,
+		logLevel: logLevel,
+		size:     int(info.Size()),
+		chunk:    chunk,
+	}
+	output.LogFunc, output.outputType = logFunc(outputType)
+	if err := output.grow(0); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return output, nil
+}
+
+// grow makes sure n more bytes can be written to the mapped region
+// by growing the file and remapping it if needed.
+//
+// o.mu must be held.
+func (o *MmapFileOutput) grow(n int) error {
+	if o.size+n <= len(o.data) && len(o.data) != 0 {
+		return nil
+	}
+	length := (o.size + n + o.chunk) / o.chunk * o.chunk
+	// the current region is kept until the new one is mapped
+	if err := o.f.Truncate(int64(length)); err != nil {
+		return err
+	}
+	data, err := mmap(o.f, length)
+	if err != nil {
+		return err
+	}
+	if o.data != nil {
+		if err := munmap(o.data); err != nil {
+			munmap(data)
+			return err
+		}
+	}
+	o.data = data
+	return nil
+}
+
+// mmapWriter is the io.Writer handed to LogFunc
+type mmapWriter MmapFileOutput
+
+func (w *mmapWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, ErrOutputClosed
+	}
+	if err := (*MmapFileOutput)(w).grow(len(p)); err != nil {
+		return 0, err
+	}
+	n := copy(w.data[w.size:], p)
+	w.size += n
+	return n, nil
+}
+
+func (o *MmapFileOutput) Log(entry *LogEntry) error {
+	if o.logLevel.Permits(entry.Level) {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		var e error
+		buf, ok := entry.GetCompiled(o.flags, o.outputType)
+		if !ok {
+			buf = entry.GetBuf()
+			e = o.LogFunc(buf, entry, o.flags, (*mmapWriter)(o))
+		} else {
+			_, e = (*mmapWriter)(o).Write(*buf)
+		}
+		return e
+	}
+	return nil
+}
+
+func (o *MmapFileOutput) OnAdd() {
+	o.add++
+}
+
+func (o *MmapFileOutput) GetFlags() int {
+	return o.flags
+}
+
+func (o *MmapFileOutput) SetFlags(flags int) {
+	o.flags = flags
+}
+
+func (o *MmapFileOutput) SetLogLevel(logLevel LogLevel) {
+	o.logLevel = logLevel
+}
+
+func (o *MmapFileOutput) GetLogLevel() LogLevel {
+	return o.logLevel
+}
+
+func (o *MmapFileOutput) GetOutputType() OutputType {
+	return o.outputType
+}
+
+// LogClose unmaps the file, truncates it to the length actually written and closes it
+func (o *MmapFileOutput) LogClose() error {
+	if o.add > 1 {
+		o.add--
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return nil
+	}
+	o.closed = true
+	var err error
+	if o.data != nil {
+		err = munmap(o.data)
+		o.data = nil
+	}
+	if e := o.f.Truncate(int64(o.size)); err == nil {
+		err = e
+	}
+	if e := o.f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
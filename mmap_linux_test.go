@@ -0,0 +1,40 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMmapFileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	out, err := NewMmapFileOutput(path, 16, F_Level|F_NewLine, L_Info, T_Text, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want strings.Builder
+	for i := 0; i < 10; i++ {
+		if err := out.Log(&LogEntry{Level: L_Info, Msg: "some message"}); err != nil {
+			t.Fatal(err)
+		}
+		want.WriteString("[INFO] some message\n")
+	}
+	if err := out.LogClose(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.LogClose(); err != nil {
+		t.Fatalf("second LogClose = %v", err)
+	}
+	if err := out.Log(&LogEntry{Level: L_Info, Msg: "late"}); err != ErrOutputClosed {
+		t.Errorf("Log after close = %v, want %v", err, ErrOutputClosed)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want.String() {
+		t.Errorf("file = %q, want %q", data, want.String())
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package log
+
+import "os"
+
+const mmapSupported = false
+
+func mmap(f *os.File, length int) ([]byte, error) {
+	return nil, ErrMmapUnsupported
+}
+
+func munmap(data []byte) error {
+	return ErrMmapUnsupported
+}
@@ -0,0 +1,465 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is reported to ErrorHandler once per export by OTLPOutput when entries were dropped
+// since the previous one as they were logged faster than they could be exported
+var ErrQueueFull = fmt.Errorf("export queue is full")
+
+// OTLPConfig configures an OTLPOutput, zero values are replaced by defaults
+type OTLPConfig struct {
+	// collector URL, '/v1/logs' is appended if missing ("http://localhost:4318" by default)
+	Endpoint string
+
+	// headers added to every request (ex: authentication)
+	Headers map[string]string
+
+	// attributes of the resource producing logs (ex: service.name), set once for every record
+	Resource M
+
+	// name of the instrumentation scope of records
+	Scope string
+
+	// maximum number of records per request (512 by default)
+	BatchSize int
+
+	// maximum number of records waiting to be exported, entries logged past it are
+	// dropped (8 * BatchSize by default)
+	MaxQueue int
+
+	// maximum time spent by LogClose exporting remaining records, records left after it
+	// are dropped (5s by default)
+	CloseTimeout time.Duration
+
+	// interval between exports of incomplete batches (5s by default)
+	Interval time.Duration
+
+	// number of attempts after a failed export (5 by default, -1 to never retry)
+	Retries int
+
+	// wait before the first retry, doubled after each retry (500ms by default)
+	Backoff time.Duration
+
+	// client used to send requests (http.Client with a 10s timeout by default)
+	Client *http.Client
+}
+
+// OTLPOutput is an Output exporting entries as OpenTelemetry log records to a collector
+// over OTLP/HTTP with JSON encoding.
+//
+// Records are exported in batches from a background goroutine, failed exports are retried
+// on network errors and on status codes 429, 502, 503 and 504. Export failures and dropped
+// entries are reported to ErrorHandler.
+type OTLPOutput struct {
+	add      int
+	flags    int
+	logLevel LogLevel
+
+	cfg      OTLPConfig
+	url      string
+	resource []byte // encoded resource and scope, shared by every request
+
+	mu      sync.Mutex
+	pending []json.RawMessage
+	closed  bool
+	dropped int // entries dropped since the output was created
+	full    int // entries dropped because the queue was full, not reported yet
+	lost    int // records dropped because LogClose timed out
+
+	flush  chan struct{}
+	stop   chan struct{}
+	wg     sync.WaitGroup
+	ctx    context.Context // canceled when LogClose times out
+	cancel context.CancelFunc
+}
+
+var otlpOutputType = NewOutputType()
+
+// NewOTLPOutput returns an OTLPOutput exporting entries according to cfg.
+//
+// Each entry is a record with timeUnixNano, severityNumber and severityText from its level and
+// body from its message. Prefixes are joined with '.' into the 'log.logger' attribute (or only
+// the last one with F_LastPrefix) and fields are added as attributes with any F_Fields_* flag.
+//
+// 'LogClose()' exports remaining records before returning, waiting at most cfg.CloseTimeout.
+//
+// The returned Output is an *OTLPOutput (ex: to call Dropped).
+func NewOTLPOutput(flags int, cfg OTLPConfig) (Output, error) {
+	setDefault(&cfg.Endpoint, "http://localhost:4318")
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 512
+	}
+	if cfg.MaxQueue <= 0 {
+		cfg.MaxQueue = 8 * cfg.BatchSize
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Second
+	}
+	if cfg.CloseTimeout <= 0 {
+		cfg.CloseTimeout = 5 * time.Second
+	}
+	if cfg.Retries == 0 {
+		cfg.Retries = 5
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = 500 * time.Millisecond
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	attrs, err := json.Marshal(otlpAttributes(cfg.Resource))
+	if err != nil {
+		return nil, err
+	}
+	scope, _ := json.Marshal(cfg.Scope)
+	// records are appended to logRecords by send
+	var resource []byte
+	resource = append(resource, `{"resource":{"attributes":`...)
+	resource = append(resource, attrs...)
+	resource = append(resource, `},"scopeLogs":[{"scope":{"name":`...)
+	resource = append(resource, scope...)
+	resource = append(resource, `},"logRecords":[`...)
+
+	o := &OTLPOutput{
+		flags:    flags,
+		logLevel: L_Info,
+		cfg:      cfg,
+		url:      strings.TrimSuffix(cfg.Endpoint, "/"),
+		resource: resource,
+		flush:    make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+	o.ctx, o.cancel = context.WithCancel(context.Background())
+	if !strings.HasSuffix(o.url, "/v1/logs") {
+		o.url += "/v1/logs"
+	}
+	o.wg.Add(1)
+	go o.run()
+	return o, nil
+}
+
+// OTLPSeverity returns the OpenTelemetry severity number of level
+func OTLPSeverity(level LogLevel) int {
+	switch level {
+	case L_Debug:
+		return 5
+	case L_Info:
+		return 9
+	case L_Warn:
+		return 13
+	case L_Error:
+		return 17
+	case L_Fatal:
+		return 21
+	default:
+		return 0
+	}
+}
+
+func (o *OTLPOutput) Log(entry *LogEntry) error {
+	if !o.logLevel.Permits(entry.Level) {
+		return nil
+	}
+	// records are copied as buffers are reused once the entry is logged
+	var record json.RawMessage
+	if buf, ok := entry.GetCompiled(o.flags, otlpOutputType); ok {
+		record = append(record, *buf...)
+	} else {
+		data, err := otlpRecord(entry, o.flags)
+		if err != nil {
+			return err
+		}
+		record = append(record, data...)
+		entry.AddCompiled(o.flags, otlpOutputType, &data)
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrOutputClosed
+	}
+	if len(o.pending) >= o.cfg.MaxQueue {
+		// counted and reported by the exporting goroutine
+		o.dropped++
+		o.full++
+		return nil
+	}
+	o.pending = append(o.pending, record)
+	if len(o.pending) >= o.cfg.BatchSize {
+		select {
+		case o.flush <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func otlpRecord(entry *LogEntry, flags int) ([]byte, error) {
+	var attrs M
+	if len(entry.Prefixes) != 0 && flags&(F_Prefix|F_LastPrefix) != 0 {
+		if flags&F_LastPrefix != 0 {
+			attrs.Add("log.logger", entry.Prefixes[len(entry.Prefixes)-1])
+		} else {
+			attrs.Add("log.logger", strings.Join(entry.Prefixes, "."))
+		}
+	}
+	if flags&(F_Fields|F_Fields_A|F_Fields_B) != 0 {
+		attrs.AddE(entry.Fields...)
+	}
+	var m = M{
+		{"timeUnixNano", strconv.FormatInt(entry.Time.UnixNano(), 10)},
+		{"observedTimeUnixNano", strconv.FormatInt(time.Now().UnixNano(), 10)},
+		{"severityNumber", OTLPSeverity(entry.Level)},
+		{"severityText", entry.Level.String()},
+		{"body", otlpValue(entry.Msg)},
+	}
+	if len(attrs) != 0 {
+		m.Add("attributes", otlpAttributes(attrs))
+	}
+	return json.Marshal(m)
+}
+
+// otlpAttributes returns m as a list of OTLP KeyValue
+func otlpAttributes(m M) []M {
+	var attrs = make([]M, 0, len(m))
+	for _, v := range m {
+		attrs = append(attrs, M{{"key", v.Key}, {"value", otlpValue(v.Val)}})
+	}
+	return attrs
+}
+
+// otlpValue returns v as an OTLP AnyValue
+func otlpValue(v interface{}) M {
+	switch v := v.(type) {
+	case string:
+		return M{{"stringValue", v}}
+	case bool:
+		return M{{"boolValue", v}}
+	case int:
+		return M{{"intValue", strconv.Itoa(v)}}
+	case int64:
+		return M{{"intValue", strconv.FormatInt(v, 10)}}
+	case float64:
+		return M{{"doubleValue", v}}
+	case M:
+		return M{{"kvlistValue", M{{"values", otlpAttributes(v)}}}}
+	case []interface{}:
+		var values = make([]M, 0, len(v))
+		for _, e := range v {
+			values = append(values, otlpValue(e))
+		}
+		return M{{"arrayValue", M{{"values", values}}}}
+	case []byte:
+		return M{{"bytesValue", v}}
+	default:
+		return M{{"stringValue", fieldString(v)}}
+	}
+}
+
+func (o *OTLPOutput) run() {
+	defer o.wg.Done()
+	ticker := time.NewTicker(o.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-o.flush:
+		case <-o.stop:
+			o.export()
+			return
+		}
+		o.export()
+	}
+}
+
+// export sends pending records until none is left and reports entries dropped
+// because the queue was full
+func (o *OTLPOutput) export() {
+	defer func() {
+		o.mu.Lock()
+		n := o.full
+		o.full = 0
+		o.mu.Unlock()
+		if n != 0 {
+			reportError(fmt.Errorf("dropped %v log entries: %w", n, ErrQueueFull))
+		}
+	}()
+	for {
+		o.mu.Lock()
+		n := len(o.pending)
+		if n > o.cfg.BatchSize {
+			n = o.cfg.BatchSize
+		}
+		batch := o.pending[:n:n]
+		o.pending = o.pending[n:]
+		if n != 0 && o.ctx.Err() != nil {
+			// LogClose timed out, remaining records are returned by it
+			o.lose(n + len(o.pending))
+			o.pending = nil
+			n = 0
+		}
+		o.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if err := o.send(batch); err != nil {
+			if o.ctx.Err() != nil {
+				o.mu.Lock()
+				o.lose(n)
+				o.mu.Unlock()
+				continue
+			}
+			reportError(fmt.Errorf("exporting %v log records: %w", n, err))
+		}
+	}
+}
+
+// lose counts n records dropped because LogClose timed out.
+//
+// o.mu must be held.
+func (o *OTLPOutput) lose(n int) {
+	o.dropped += n
+	o.lost += n
+}
+
+// send posts records to the collector, retrying as configured
+func (o *OTLPOutput) send(records []json.RawMessage) error {
+	var body = make([]byte, 0, 4096)
+	body = append(body, `{"resourceLogs":[`...)
+	body = append(body, o.resource...)
+	for i, r := range records {
+		if i != 0 {
+			body = append(body, ',')
+		}
+		body = append(body, r...)
+	}
+	body = append(body, "]}]}]}"...)
+
+	var err error
+	var backoff = o.cfg.Backoff
+	for i := 0; i == 0 || i <= o.cfg.Retries; i++ {
+		if i != 0 {
+			t := time.NewTimer(backoff)
+			select {
+			case <-t.C:
+			case <-o.ctx.Done():
+				t.Stop()
+				return err
+			}
+			backoff *= 2
+		}
+		var retry bool
+		if retry, err = o.post(body); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// post sends one request, it returns wether it can be retried on failure
+func (o *OTLPOutput) post(body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(o.ctx, http.MethodPost, o.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range o.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := o.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return false, nil
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true, fmt.Errorf("collector responded %v", resp.Status)
+	default:
+		return false, fmt.Errorf("collector responded %v", resp.Status)
+	}
+}
+
+// Dropped returns the number of entries dropped since o was created, either because they were
+// logged while the queue was full or because LogClose timed out before they were exported
+func (o *OTLPOutput) Dropped() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.dropped
+}
+
+func (o *OTLPOutput) OnAdd() {
+	o.add++
+}
+
+func (o *OTLPOutput) GetFlags() int {
+	return o.flags
+}
+
+func (o *OTLPOutput) SetFlags(flags int) {
+	o.flags = flags
+}
+
+func (o *OTLPOutput) SetLogLevel(logLevel LogLevel) {
+	o.logLevel = logLevel
+}
+
+func (o *OTLPOutput) GetLogLevel() LogLevel {
+	return o.logLevel
+}
+
+func (o *OTLPOutput) GetOutputType() OutputType {
+	return otlpOutputType
+}
+
+func (o *OTLPOutput) LogClose() error {
+	if o.add > 1 {
+		o.add--
+		return nil
+	}
+	o.mu.Lock()
+	if o.closed {
+		o.mu.Unlock()
+		return nil
+	}
+	o.closed = true
+	o.mu.Unlock()
+	close(o.stop)
+
+	done := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(o.cfg.CloseTimeout)
+	select {
+	case <-done:
+		timer.Stop()
+	case <-timer.C:
+		// aborts the request in flight and retries
+		o.cancel()
+		<-done
+	}
+	o.cancel()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.lost != 0 {
+		return fmt.Errorf("%v log records not exported within %v", o.lost, o.cfg.CloseTimeout)
+	}
+	return nil
+}
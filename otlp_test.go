@@ -0,0 +1,178 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collector records the number of log records of each request it receives
+type collector struct {
+	mu       sync.Mutex
+	batches  []int
+	fail     int // number of requests to answer with 503
+	requests int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.requests++
+	if r.URL.Path != "/v1/logs" || r.Header.Get("Authorization") != "token" || r.Header.Get("Content-Type") != "application/json" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if c.fail > 0 {
+		c.fail--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var body struct {
+		ResourceLogs []struct {
+			ScopeLogs []struct {
+				LogRecords []json.RawMessage
+			}
+		}
+	}
+	data, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(data, &body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	c.batches = append(c.batches, len(body.ResourceLogs[0].ScopeLogs[0].LogRecords))
+}
+
+// handleErrors collects errors reported to ErrorHandler until the test ends
+func handleErrors(t *testing.T) func() []error {
+	var mu sync.Mutex
+	var errs []error
+	h := ErrorHandler
+	t.Cleanup(func() { ErrorHandler = h })
+	ErrorHandler = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+	return func() []error {
+		mu.Lock()
+		defer mu.Unlock()
+		return errs
+	}
+}
+
+func logN(t *testing.T, o Output, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := o.Log(&LogEntry{Time: time.Now(), Level: L_Info, Msg: "hi", Fields: M{{"i", i}}}); err != nil {
+			t.Fatalf("Log = %v", err)
+		}
+	}
+}
+
+func TestOTLPBatchesAndRetries(t *testing.T) {
+	errs := handleErrors(t)
+	c := &collector{fail: 2}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	o, err := NewOTLPOutput(F_Std, OTLPConfig{
+		Endpoint:  srv.URL,
+		Headers:   map[string]string{"Authorization": "token"},
+		BatchSize: 4,
+		MaxQueue:  100,
+		Interval:  time.Hour,
+		Backoff:   time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logN(t, o, 10)
+	if err := o.LogClose(); err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var total int
+	for _, n := range c.batches {
+		if n > 4 {
+			t.Errorf("batch of %v records, want at most 4", n)
+		}
+		total += n
+	}
+	if total != 10 {
+		t.Errorf("%v records exported in %v, want 10", total, c.batches)
+	}
+	if c.requests != len(c.batches)+2 {
+		t.Errorf("%v requests for %v batches, want 2 retries", c.requests, len(c.batches))
+	}
+	if n := o.(*OTLPOutput).Dropped(); len(errs()) != 0 || n != 0 {
+		t.Errorf("errors = %v, dropped = %v", errs(), n)
+	}
+}
+
+func TestOTLPDropped(t *testing.T) {
+	errs := handleErrors(t)
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	o, err := NewOTLPOutput(F_Std, OTLPConfig{
+		Endpoint:  srv.URL,
+		Headers:   map[string]string{"Authorization": "token"},
+		BatchSize: 100,
+		MaxQueue:  5,
+		Interval:  time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logN(t, o, 8)
+	if err := o.LogClose(); err != nil {
+		t.Fatal(err)
+	}
+	if n := o.(*OTLPOutput).Dropped(); n != 3 {
+		t.Errorf("Dropped = %v, want 3", n)
+	}
+	if e := errs(); len(e) != 1 || !errors.Is(e[0], ErrQueueFull) {
+		t.Errorf("errors = %v, want one ErrQueueFull", e)
+	}
+}
+
+func TestOTLPCloseTimeout(t *testing.T) {
+	handleErrors(t)
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	o, err := NewOTLPOutput(F_Std, OTLPConfig{
+		Endpoint:     srv.URL,
+		BatchSize:    2,
+		Interval:     time.Hour,
+		CloseTimeout: 100 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logN(t, o, 5)
+	start := time.Now()
+	if err := o.LogClose(); err == nil {
+		t.Error("expected an error for records not exported")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("LogClose took %v", d)
+	}
+	if n := o.(*OTLPOutput).Dropped(); n != 5 {
+		t.Errorf("Dropped = %v, want 5", n)
+	}
+}
@@ -1,8 +1,12 @@
 package log
 
 import (
-	"encoding/json"
+	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // Output represents a log writer.
@@ -63,12 +67,13 @@ type outputWrapper struct {
 // responsible for closing the io.Writer. The bool argument is the close bool parameter of NewOutputWrapper
 func NewOutputWrapper(w io.Writer, close bool, flags int, output OutputType, logLevel LogLevel, logFunc func(*[]byte, *LogEntry, int, io.Writer) error, closeFunc func(io.Writer, bool) error) Output {
 	return &outputWrapper{
-		w:         w,
-		close:     close,
-		flags:     flags,
-		logLevel:  logLevel,
-		LogFunc:   logFunc,
-		CloseFunc: closeFunc,
+		w:          w,
+		close:      close,
+		flags:      flags,
+		logLevel:   logLevel,
+		outputType: output,
+		LogFunc:    logFunc,
+		CloseFunc:  closeFunc,
 	}
 }
 
@@ -132,67 +137,32 @@ func DefaultCloseFunc(w io.Writer, close bool) error {
 }
 
 // JSONLogFunc formats entry with specified flags and writes it to w as a JSON object
-// adding buf to entry with TEXT output type
+// adding buf to entry with JSON output type
 func JSONLogFunc(buf *[]byte, entry *LogEntry, flags int, w io.Writer) error {
-	var m = M{}
-	if flags&F_Time != 0 {
-		m.Add("time", entry.Time)
-	}
-
-	if entry.Prefixes != nil && len(entry.Prefixes) != 0 && flags&(F_Prefix|F_LastPrefix) != 0 {
-		if flags&F_LastPrefix != 0 {
-			m.Add("prefix", entry.Prefixes[len(entry.Prefixes)-1])
-		} else {
-			m.Add("prefix", append([]string{}, entry.Prefixes...))
-		}
-	}
-
-	if flags&F_Level != 0 {
-		m.Add("level", entry.Level.String())
-	}
-	if entry.Fields != nil && len(entry.Fields) != 0 && flags&(F_Fields|F_Fields_A|F_Fields_B) != 0 {
-		if flags&F_Fields_A != 0 {
-			m.Add("fields", entry.Fields)
-		} else if flags&F_Fields != 0 {
-			m.AddE(entry.Fields...)
-		} else if flags&F_Fields_B != 0 {
-			m.Add("fields", entry.Fields.AsArray())
-		}
-	}
-
-	m.Add("msg", entry.Msg)
-
-	data, _ := json.Marshal(m)
-	entry.AddCompiled(flags, T_JSON, &data)
-	entry.AddCompiled(F_NotSave, T_JSON, buf)
-	if flags&F_NewLine != 0 {
-		data = append(data, '\n')
-	}
-	_, err := w.Write(data)
-	return err
+	return jsonLogFunc(buf, entry, flags, w, nil, nil, T_JSON)
 }
 
 // TextLogFunc uses buf to format entry with specified flags and writes it to w as a line
 // adding buf to entry with TEXT output type
 func TextLogFunc(buf *[]byte, entry *LogEntry, flags int, w io.Writer) error {
-	if flags&(F_Time|F_Micro) != 0 {
-		year, month, day := entry.Time.Date()
-		appendInt(buf, day, 2)
-		*buf = append(*buf, '/')
-		appendInt(buf, int(month), 2)
-		*buf = append(*buf, '/')
-		appendInt(buf, year, 4)
-		*buf = append(*buf, ' ')
+	return textLogFunc(buf, entry, flags, w, TimeFormat{}, T_Text)
+}
 
-		appendInt(buf, entry.Time.Hour(), 2)
-		*buf = append(*buf, ':')
-		appendInt(buf, entry.Time.Minute(), 2)
-		*buf = append(*buf, ':')
-		appendInt(buf, entry.Time.Second(), 2)
-		if flags&F_Micro != 0 {
-			*buf = append(*buf, '.')
-			appendInt(buf, entry.Time.Nanosecond()/1000, 6)
-		}
+// NewTextLogFunc returns a LogFunc similar to TextLogFunc but formatting time with tf,
+// along with the OutputType it adds buffers with (T_Text for the default TimeFormat).
+func NewTextLogFunc(tf TimeFormat) (func(*[]byte, *LogEntry, int, io.Writer) error, OutputType) {
+	if tf == (TimeFormat{}) {
+		return TextLogFunc, T_Text
+	}
+	outputType := NewOutputType()
+	return func(buf *[]byte, entry *LogEntry, flags int, w io.Writer) error {
+		return textLogFunc(buf, entry, flags, w, tf, outputType)
+	}, outputType
+}
+
+func textLogFunc(buf *[]byte, entry *LogEntry, flags int, w io.Writer, tf TimeFormat, outputType OutputType) error {
+	if flags&(F_Time|F_Micro) != 0 {
+		tf.appendTime(buf, entry.Time, flags&F_Micro != 0)
 		*buf = append(*buf, ' ')
 	}
 
@@ -216,16 +186,124 @@ func TextLogFunc(buf *[]byte, entry *LogEntry, flags int, w io.Writer) error {
 		*buf = append(*buf, "] "...)
 	}
 
-	*buf = append(*buf, entry.Msg...)
-	if flags&F_NewLine != 0 && entry.Msg[len(entry.Msg)-1] != '\n' {
+	if len(entry.Fields) != 0 && flags&(F_Fields|F_Fields_A|F_Fields_B) != 0 {
+		msg := strings.TrimSuffix(entry.Msg, "\n")
+		*buf = append(*buf, msg...)
+		appendTextFields(buf, entry.Fields, flags)
+		if len(msg) != len(entry.Msg) {
+			*buf = append(*buf, '\n')
+		}
+	} else {
+		*buf = append(*buf, entry.Msg...)
+	}
+	if flags&F_NewLine != 0 && (len(*buf) == 0 || (*buf)[len(*buf)-1] != '\n') {
 		*buf = append(*buf, '\n')
 	}
 
-	entry.AddCompiled(flags, T_Text, buf)
+	entry.AddCompiled(flags, outputType, buf)
 	_, err := w.Write(*buf)
 	return err
 }
 
+// appendTextFields appends fields after a space in the style selected by flags:
+//
+//	F_Fields_A: {key: value, key2: "value 2"}
+//	F_Fields:   key=value key2="value 2"
+//	F_Fields_B: [key=value] [key2="value 2"]
+func appendTextFields(buf *[]byte, fields M, flags int) {
+	switch {
+	case flags&F_Fields_A != 0:
+		*buf = append(*buf, " {"...)
+		for i, v := range fields {
+			if i != 0 {
+				*buf = append(*buf, ", "...)
+			}
+			appendQuoted(buf, v.Key, ":,{}")
+			*buf = append(*buf, ": "...)
+			appendQuoted(buf, fieldString(v.Val), ",{}")
+		}
+		*buf = append(*buf, '}')
+	case flags&F_Fields != 0:
+		for _, v := range fields {
+			*buf = append(*buf, ' ')
+			appendQuoted(buf, v.Key, "")
+			*buf = append(*buf, '=')
+			appendQuoted(buf, fieldString(v.Val), "")
+		}
+	case flags&F_Fields_B != 0:
+		for _, v := range fields {
+			*buf = append(*buf, " ["...)
+			appendQuoted(buf, v.Key, "[]")
+			*buf = append(*buf, '=')
+			appendQuoted(buf, fieldString(v.Val), "[]")
+			*buf = append(*buf, ']')
+		}
+	}
+}
+
+// fieldString returns the text representation of a field value
+func fieldString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case error:
+		return v.Error()
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// appendQuoted appends s, quoted as a Go string if it is empty or contains
+// spaces, quotes, '=', control characters or any of delims
+func appendQuoted(buf *[]byte, s string, delims string) {
+	if needsQuote(s, delims) {
+		*buf = strconv.AppendQuote(*buf, s)
+	} else {
+		*buf = append(*buf, s...)
+	}
+}
+
+func needsQuote(s string, delims string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r <= ' ' || r == '"' || r == '=' || r == 0x7f || r == utf8.RuneError || strings.ContainsRune(delims, r) {
+			return true
+		}
+	}
+	return false
+}
+
+// logFunc returns the LogFunc of a builtin output type,
+// defaulting to Text for unknown types
+func logFunc(outputType OutputType) (func(*[]byte, *LogEntry, int, io.Writer) error, OutputType) {
+	switch outputType {
+	case T_JSON:
+		return JSONLogFunc, T_JSON
+	case T_Logfmt:
+		return LogfmtLogFunc, T_Logfmt
+	case T_ECS:
+		return ECSLogFunc, T_ECS
+	default:
+		return TextLogFunc, T_Text
+	}
+}
+
 func appendInt(buf *[]byte, n int, w int) {
 	var b [20]byte
 	ind := len(b) - 1
@@ -263,3 +341,17 @@ func NewTextOutput(w io.Writer, flags int, close bool) Output {
 		CloseFunc:  DefaultCloseFunc,
 	}
 }
+
+// same as NewTextOutput() but formats time with tf
+func NewTextOutputWithTime(w io.Writer, flags int, tf TimeFormat, close bool) Output {
+	logFunc, outputType := NewTextLogFunc(tf)
+	return &outputWrapper{
+		w:          w,
+		close:      close,
+		flags:      flags,
+		logLevel:   L_Info,
+		outputType: outputType,
+		LogFunc:    logFunc,
+		CloseFunc:  DefaultCloseFunc,
+	}
+}
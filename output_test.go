@@ -0,0 +1,38 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTextFields(t *testing.T) {
+	fields := M{
+		{"k", "v"}, {"sp", "a b"}, {"eq", "a=b"}, {"q", `say "hi"`},
+		{"br", "x]"}, {"cb", "{x}"}, {"a:b", 3}, {"empty", ""},
+	}
+	for _, tt := range []struct {
+		flags  int
+		fields M
+		want   string
+	}{
+		{F_Prefix | F_Level | F_Fields | F_NewLine, fields,
+			`[app] [INFO] done k=v sp="a b" eq="a=b" q="say \"hi\"" br=x] cb={x} a:b=3 empty=""` + "\n"},
+		{F_Fields_A | F_NewLine, fields,
+			`done {k: v, sp: "a b", eq: "a=b", q: "say \"hi\"", br: x], cb: "{x}", "a:b": 3, empty: ""}` + "\n"},
+		{F_Fields_B | F_NewLine, fields,
+			`done [k=v] [sp="a b"] [eq="a=b"] [q="say \"hi\""] [br="x]"] [cb={x}] [a:b=3] [empty=""]` + "\n"},
+		// F_Fields_A takes precedence
+		{F_Fields | F_Fields_A | F_Fields_B | F_NewLine, M{{"k", "v"}}, "done {k: v}\n"},
+		{F_Fields | F_NewLine, nil, "done\n"},
+		{F_NewLine, fields, "done\n"},
+	} {
+		var w bytes.Buffer
+		entry := &LogEntry{Level: L_Info, Msg: "done\n", Prefixes: []string{"app"}, Fields: tt.fields}
+		if err := TextLogFunc(entry.GetBuf(), entry, tt.flags, &w); err != nil {
+			t.Fatal(err)
+		}
+		if w.String() != tt.want {
+			t.Errorf("flags %b:\ngot  %q\nwant %q", tt.flags, w.String(), tt.want)
+		}
+	}
+}
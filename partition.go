@@ -0,0 +1,216 @@
+package log
+
+import (
+	"container/list"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// PartitionKey is replaced by the partition key in the path given to NewPartitionOutput
+	PartitionKey = "{key}"
+
+	// DefaultPartition is the key used for entries without partition key
+	DefaultPartition = "default"
+)
+
+type partition struct {
+	key string
+	out *FileOutput
+}
+
+// PartitionOutput is an Output writing entries to a different file
+// for each value of a partition key.
+type PartitionOutput struct {
+	add        int
+	flags      int
+	logLevel   LogLevel
+	outputType OutputType
+
+	path     string
+	field    string
+	capacity int
+	opts     []FileOption
+
+	mu     sync.Mutex
+	files  map[string]*list.Element
+	lru    *list.List      // most recently used first
+	opened map[string]bool // keys opened at least once
+	closed bool
+}
+
+// NewPartitionOutput returns an Output writing each entry to the file at path
+// with PartitionKey replaced by the partition key of the entry
+// (ex: "logs/{key}.log").
+//
+// The partition key is the value of the first field named field in LogEntry.Fields
+// or, if field is empty, the last prefix of the entry. Entries without
+// partition key are written in the DefaultPartition.
+//
+// Files are opened lazily, in append mode, as by NewFileOutput with opts and at
+// most capacity files are kept open at once (the least recently used file is
+// closed first). All files are closed with 'LogClose()'.
+//
+// WithExclusive only applies the first time each file is opened, not when it is
+// opened again after being closed.
+func NewPartitionOutput(path string, field string, capacity int, flags int, logLevel LogLevel, outputType OutputType, opts ...FileOption) (Output, error) {
+	if !strings.Contains(path, PartitionKey) {
+		return nil, fmt.Errorf("partition path %q does not contain %v", path, PartitionKey)
+	}
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &PartitionOutput{
+		flags:      flags,
+		logLevel:   logLevel,
+		outputType: outputType,
+		path:       path,
+		field:      field,
+		capacity:   capacity,
+		opts:       opts,
+		files:      map[string]*list.Element{},
+		opened:     map[string]bool{},
+		lru:        list.New(),
+	}, nil
+}
+
+// key returns the partition key of entry, sanitized to be used in a path
+func (o *PartitionOutput) key(entry *LogEntry) string {
+	var key string
+	if o.field == "" {
+		if len(entry.Prefixes) != 0 {
+			key = entry.Prefixes[len(entry.Prefixes)-1]
+		}
+	} else {
+		for _, v := range entry.Fields {
+			if v.Key == o.field {
+				key = fmt.Sprint(v.Val)
+				break
+			}
+		}
+	}
+	if key == "" {
+		return DefaultPartition
+	}
+	key = strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', 0:
+			return '_'
+		}
+		return r
+	}, key)
+	if key == "." || key == ".." {
+		key = strings.Repeat("_", len(key))
+	}
+	return key
+}
+
+// file returns the output of partition key, opening it if needed.
+//
+// o.mu must be held.
+func (o *PartitionOutput) file(key string) (*FileOutput, error) {
+	if e, ok := o.files[key]; ok {
+		o.lru.MoveToFront(e)
+		return e.Value.(*partition).out, nil
+	}
+	var opts = o.opts
+	if o.opened[key] {
+		// the file was created by this output
+		opts = append(opts[:len(opts):len(opts)], func(o *FileOutput) { o.exclusive = false })
+	}
+	out, err := newFileOutput(strings.ReplaceAll(o.path, PartitionKey, key), false, o.flags, o.logLevel, o.outputType, true, opts...)
+	if err != nil {
+		return nil, err
+	}
+	o.opened[key] = true
+	o.files[key] = o.lru.PushFront(&partition{key, out})
+	for o.lru.Len() > o.capacity {
+		if err := o.evict(o.lru.Back()); err != nil {
+			reportError(err)
+		}
+	}
+	return out, nil
+}
+
+// o.mu must be held.
+func (o *PartitionOutput) evict(e *list.Element) error {
+	p := o.lru.Remove(e).(*partition)
+	delete(o.files, p.key)
+	return p.out.LogClose()
+}
+
+func (o *PartitionOutput) Log(entry *LogEntry) error {
+	if !o.logLevel.Permits(entry.Level) {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrOutputClosed
+	}
+	key := o.key(entry)
+	out, err := o.file(key)
+	if err != nil {
+		return err
+	}
+	err = out.Log(entry)
+	if err == ErrOutputClosed {
+		// only this partition is closed, it will be reopened by next entry
+		// (ErrOutputClosed would remove every partition from log managers)
+		o.evict(o.files[key])
+		return fmt.Errorf("partition %v: file closed", key)
+	}
+	return err
+}
+
+func (o *PartitionOutput) OnAdd() {
+	o.add++
+}
+
+func (o *PartitionOutput) GetFlags() int {
+	return o.flags
+}
+
+func (o *PartitionOutput) SetFlags(flags int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.flags = flags
+	for e := o.lru.Front(); e != nil; e = e.Next() {
+		e.Value.(*partition).out.SetFlags(flags)
+	}
+}
+
+func (o *PartitionOutput) SetLogLevel(logLevel LogLevel) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.logLevel = logLevel
+	for e := o.lru.Front(); e != nil; e = e.Next() {
+		e.Value.(*partition).out.SetLogLevel(logLevel)
+	}
+}
+
+func (o *PartitionOutput) GetLogLevel() LogLevel {
+	return o.logLevel
+}
+
+func (o *PartitionOutput) GetOutputType() OutputType {
+	return o.outputType
+}
+
+func (o *PartitionOutput) LogClose() error {
+	if o.add > 1 {
+		o.add--
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.closed = true
+	var err error
+	for o.lru.Len() != 0 {
+		if e := o.evict(o.lru.Front()); err == nil {
+			err = e
+		}
+	}
+	return err
+}
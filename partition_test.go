@@ -0,0 +1,77 @@
+package log
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPartitionClosedFile(t *testing.T) {
+	dir := t.TempDir()
+	out, err := NewPartitionOutput(filepath.Join(dir, "{key}.log"), "tenant", 4, F_NewLine, L_Info, T_Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.LogClose()
+	p := out.(*PartitionOutput)
+
+	log := func(tenant string, msg string) error {
+		return out.Log(&LogEntry{Level: L_Info, Msg: msg, Fields: M{{"tenant", tenant}}})
+	}
+	for _, tenant := range []string{"a", "b"} {
+		if err := log(tenant, "first"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p.files["a"].Value.(*partition).out.LogFunc = func(*[]byte, *LogEntry, int, io.Writer) error {
+		return ErrOutputClosed
+	}
+	if err := log("a", "lost"); err == nil || err == ErrOutputClosed {
+		t.Fatalf("Log = %v, want an error other than ErrOutputClosed", err)
+	}
+	if _, ok := p.files["a"]; ok {
+		t.Fatal("closed partition is still open")
+	}
+	for _, tenant := range []string{"a", "b"} {
+		if err := log(tenant, "second"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tenant := range []string{"a", "b"} {
+		data, err := os.ReadFile(filepath.Join(dir, tenant+".log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "first\nsecond\n" {
+			t.Errorf("%v.log = %q", tenant, data)
+		}
+	}
+}
+
+func TestPartitionExclusiveReopen(t *testing.T) {
+	dir := t.TempDir()
+	out, err := NewPartitionOutput(filepath.Join(dir, "{key}.log"), "tenant", 1, F_NewLine, L_Info, T_Text, WithExclusive())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.LogClose()
+	for _, tenant := range []string{"a", "b", "a", "b"} {
+		if err := out.Log(&LogEntry{Level: L_Info, Msg: tenant, Fields: M{{"tenant", tenant}}}); err != nil {
+			t.Fatalf("logging to %v: %v", tenant, err)
+		}
+	}
+	for _, tenant := range []string{"a", "b"} {
+		if data, err := os.ReadFile(filepath.Join(dir, tenant+".log")); err != nil || string(data) != tenant+"\n"+tenant+"\n" {
+			t.Errorf("%v.log = %q, %v", tenant, data, err)
+		}
+	}
+
+	// files existing before the output are still refused
+	touch(t, filepath.Join(dir, "c.log"), 0)
+	if err := out.Log(&LogEntry{Level: L_Info, Msg: "c", Fields: M{{"tenant", "c"}}}); err == nil {
+		t.Error("expected an error for a file created by someone else")
+	}
+}
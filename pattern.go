@@ -0,0 +1,271 @@
+package log
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// named time layouts accepted by %time
+var timeLayouts = map[string]string{
+	"ISOMilli":    TimeISOMilli,
+	"unix":        TimeUnix,
+	"unixmilli":   TimeUnixMilli,
+	"ANSIC":       time.ANSIC,
+	"UnixDate":    time.UnixDate,
+	"RFC822":      time.RFC822,
+	"RFC822Z":     time.RFC822Z,
+	"RFC850":      time.RFC850,
+	"RFC1123":     time.RFC1123,
+	"RFC1123Z":    time.RFC1123Z,
+	"RFC3339":     time.RFC3339,
+	"RFC3339Nano": time.RFC3339Nano,
+	"Kitchen":     time.Kitchen,
+	"Stamp":       time.Stamp,
+	"StampMilli":  time.StampMilli,
+	"StampMicro":  time.StampMicro,
+	"StampNano":   time.StampNano,
+}
+
+// segment appends a part of a formatted entry to buf
+type segment func(buf *[]byte, entry *LogEntry, flags int)
+
+// Pattern is a compiled user-supplied text layout (see CompilePattern)
+type Pattern struct {
+	segments   []segment
+	outputType OutputType
+}
+
+// CompilePattern compiles pattern into a Pattern formatting entries by replacing
+// the following directives, arguments between braces being optional:
+//
+//	%time{layout}    time of the entry formatted with a time.Time layout, with the name
+//	                 of a layout of the time package (ex: %time{RFC3339}) or with ISOMilli,
+//	                 unix or unixmilli (see TimeFormat), defaults to TextLogFunc's.
+//	                 A time zone can be given after a '|' (ex: %time{RFC3339|UTC})
+//	%level{width}    level of the entry padded with spaces to width characters, aligned
+//	                 on the left if width is negative (ex: %level{-5} gives 'INFO ')
+//	%prefix{sep}     prefixes of the entry joined with sep ('.' by default),
+//	                 %prefix{last} only writes the last prefix
+//	%msg             message of the entry
+//	%fields{style}   fields of the entry with style 'kv' (key=value), 'object' ({key: value})
+//	                 or 'brackets' ([key=value]), defaults to the style selected by flags (see F_Fields).
+//	                 Spaces right before %fields are only written if the entry has fields
+//	%%               a literal '%'
+//
+// ex: "%time{RFC3339} %level{-5} %prefix{last} %msg %fields"
+//
+// Flags are not used to select what is written, apart from F_NewLine that adds a '\n'
+// at the end of each line if not already present.
+//
+// Each Pattern has its own OutputType so that Patterns can be used with NewOutputWrapper:
+//
+//	NewOutputWrapper(w, close, flags, p.OutputType(), L_Info, p.LogFunc, DefaultCloseFunc)
+func CompilePattern(pattern string) (*Pattern, error) {
+	var p = &Pattern{outputType: NewOutputType()}
+	var text []byte
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' {
+			text = append(text, pattern[i])
+			continue
+		}
+		if i+1 < len(pattern) && pattern[i+1] == '%' {
+			text = append(text, '%')
+			i++
+			continue
+		}
+
+		j := i + 1
+		for j < len(pattern) && pattern[j] >= 'a' && pattern[j] <= 'z' {
+			j++
+		}
+		name := pattern[i+1 : j]
+		var arg string
+		var hasArg bool
+		if j < len(pattern) && pattern[j] == '{' {
+			end := strings.IndexByte(pattern[j:], '}')
+			if end == -1 {
+				return nil, fmt.Errorf("pattern: unclosed '{' after %%%v", name)
+			}
+			arg, hasArg = pattern[j+1:j+end], true
+			j += end + 1
+		}
+
+		seg, err := compileDirective(name, arg, hasArg)
+		if err != nil {
+			return nil, err
+		}
+		if name == "fields" {
+			// %fields owns the spaces before it so that none is left for entries without fields
+			n := len(text)
+			for n > 0 && text[n-1] == ' ' {
+				n--
+			}
+			if n != len(text) {
+				seg = separated(string(text[n:]), seg)
+				text = text[:n]
+			}
+		}
+		if len(text) != 0 {
+			p.segments = append(p.segments, textSegment(string(text)))
+			text = text[:0]
+		}
+		p.segments = append(p.segments, seg)
+		i = j - 1
+	}
+	if len(text) != 0 {
+		p.segments = append(p.segments, textSegment(string(text)))
+	}
+	return p, nil
+}
+
+func textSegment(s string) segment {
+	return func(buf *[]byte, entry *LogEntry, flags int) {
+		*buf = append(*buf, s...)
+	}
+}
+
+// separated returns a segment writing sep before what seg writes, if anything
+func separated(sep string, seg segment) segment {
+	return func(buf *[]byte, entry *LogEntry, flags int) {
+		start := len(*buf)
+		*buf = append(*buf, sep...)
+		seg(buf, entry, flags)
+		if len(*buf) == start+len(sep) {
+			*buf = (*buf)[:start]
+		}
+	}
+}
+
+func compileDirective(name string, arg string, hasArg bool) (segment, error) {
+	switch name {
+	case "time":
+		var tf TimeFormat
+		if i := strings.LastIndexByte(arg, '|'); i != -1 {
+			loc, err := time.LoadLocation(arg[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("pattern: %w", err)
+			}
+			tf.Location = loc
+			arg = arg[:i]
+		}
+		tf.Layout = arg
+		if l, ok := timeLayouts[arg]; ok {
+			tf.Layout = l
+		}
+		return func(buf *[]byte, entry *LogEntry, flags int) {
+			tf.appendTime(buf, entry.Time, flags&F_Micro != 0)
+		}, nil
+
+	case "level":
+		var width int
+		if hasArg {
+			var err error
+			if width, err = strconv.Atoi(arg); err != nil {
+				return nil, fmt.Errorf("pattern: invalid %%level width %q", arg)
+			}
+		}
+		return func(buf *[]byte, entry *LogEntry, flags int) {
+			appendPadded(buf, entry.Level.String(), width)
+		}, nil
+
+	case "prefix":
+		if arg == "last" {
+			return func(buf *[]byte, entry *LogEntry, flags int) {
+				if len(entry.Prefixes) != 0 {
+					*buf = append(*buf, entry.Prefixes[len(entry.Prefixes)-1]...)
+				}
+			}, nil
+		}
+		sep := "."
+		if hasArg {
+			sep = arg
+		}
+		return func(buf *[]byte, entry *LogEntry, flags int) {
+			for i, v := range entry.Prefixes {
+				if i != 0 {
+					*buf = append(*buf, sep...)
+				}
+				*buf = append(*buf, v...)
+			}
+		}, nil
+
+	case "msg":
+		return func(buf *[]byte, entry *LogEntry, flags int) {
+			*buf = append(*buf, strings.TrimSuffix(entry.Msg, "\n")...)
+		}, nil
+
+	case "fields":
+		var style int
+		switch arg {
+		case "":
+		case "kv":
+			style = F_Fields
+		case "object":
+			style = F_Fields_A
+		case "brackets":
+			style = F_Fields_B
+		default:
+			return nil, fmt.Errorf("pattern: unknown %%fields style %q", arg)
+		}
+		return func(buf *[]byte, entry *LogEntry, flags int) {
+			if len(entry.Fields) == 0 {
+				return
+			}
+			s := style
+			if s == 0 {
+				s = flags & (F_Fields | F_Fields_A | F_Fields_B)
+				if s == 0 {
+					s = F_Fields
+				}
+			}
+			// appendTextFields starts with a space
+			start := len(*buf)
+			appendTextFields(buf, entry.Fields, s)
+			*buf = append((*buf)[:start], (*buf)[start+1:]...)
+		}, nil
+	}
+	return nil, fmt.Errorf("pattern: unknown directive %%%v", name)
+}
+
+// appendPadded appends s padded with spaces to |width| characters,
+// aligned on the left (padded on the right) if width is negative
+func appendPadded(buf *[]byte, s string, width int) {
+	if width < 0 {
+		*buf = append(*buf, s...)
+		for i := len(s); i < -width; i++ {
+			*buf = append(*buf, ' ')
+		}
+		return
+	}
+	for i := len(s); i < width; i++ {
+		*buf = append(*buf, ' ')
+	}
+	*buf = append(*buf, s...)
+}
+
+// OutputType returns the OutputType of buffers compiled by p
+func (p *Pattern) OutputType() OutputType {
+	return p.outputType
+}
+
+// LogFunc uses buf to format entry according to p and writes it to w
+// adding buf to entry with p's output type
+func (p *Pattern) LogFunc(buf *[]byte, entry *LogEntry, flags int, w io.Writer) error {
+	for _, seg := range p.segments {
+		seg(buf, entry, flags)
+	}
+	if flags&F_NewLine != 0 && (len(*buf) == 0 || (*buf)[len(*buf)-1] != '\n') {
+		*buf = append(*buf, '\n')
+	}
+	entry.AddCompiled(flags, p.outputType, buf)
+	_, err := w.Write(*buf)
+	return err
+}
+
+// NewPatternOutput returns an Output formatting entries with p
+func NewPatternOutput(w io.Writer, p *Pattern, flags int, close bool) Output {
+	return NewOutputWrapper(w, close, flags, p.outputType, L_Info, p.LogFunc, DefaultCloseFunc)
+}
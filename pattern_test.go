@@ -0,0 +1,42 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPattern(t *testing.T) {
+	for _, test := range []struct {
+		pattern string
+		entry   *LogEntry
+		want    string
+	}{
+		{"%msg %fields", &LogEntry{Msg: "hi"}, "hi\n"},
+		{"%msg %fields", &LogEntry{Msg: "hi", Fields: M{{"k", "v"}}}, "hi k=v\n"},
+		{"%msg  %fields{brackets}", &LogEntry{Msg: "hi", Fields: M{{"k", "v w"}}}, "hi  [k=\"v w\"]\n"},
+		{"[%level{-5}] %msg", &LogEntry{Level: L_Info, Msg: "hi"}, "[INFO ] hi\n"},
+		{"[%level{5}] %msg", &LogEntry{Level: L_Info, Msg: "hi"}, "[ INFO] hi\n"},
+		{"%prefix %prefix{last} %prefix{/}: %msg", &LogEntry{Prefixes: []string{"app", "db"}, Msg: "hi\n"}, "app.db db app/db: hi\n"},
+		{"100%% %msg", &LogEntry{Msg: "hi"}, "100% hi\n"},
+	} {
+		p, err := CompilePattern(test.pattern)
+		if err != nil {
+			t.Fatalf("%q: %v", test.pattern, err)
+		}
+		var w bytes.Buffer
+		if err := p.LogFunc(test.entry.GetBuf(), test.entry, F_NewLine, &w); err != nil {
+			t.Fatal(err)
+		}
+		if w.String() != test.want {
+			t.Errorf("%q: got %q, want %q", test.pattern, w.String(), test.want)
+		}
+	}
+}
+
+func TestPatternErrors(t *testing.T) {
+	for _, pattern := range []string{"%unknown", "%level{x}", "%fields{x}", "%time{RFC3339|Nowhere/Zone}", "%msg{"} {
+		if _, err := CompilePattern(pattern); err == nil {
+			t.Errorf("%q: expected an error", pattern)
+		}
+	}
+}
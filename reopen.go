@@ -0,0 +1,58 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// WithReopenOnSignal makes FileOutput call Reopen each time the process
+// receives one of sig (SIGHUP if none is given), until LogClose.
+//
+// It is intended for use with external tools such as logrotate
+// that rename the file being written.
+func WithReopenOnSignal(sig ...os.Signal) FileOption {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	return func(o *FileOutput) {
+		o.signals = sig
+	}
+}
+
+// Reopen closes the current file and opens its path again, creating it if needed.
+//
+// Reopen waits for the current log call to end so that no entry is split across files.
+//
+// ex: out.(*FileOutput).Reopen() with out returned by NewFileOutput
+func (o *FileOutput) Reopen() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.closed {
+		return ErrOutputClosed
+	}
+	f, err := o.open(o.path, appendFlags)
+	if err != nil {
+		return err
+	}
+	return o.replace(f)
+}
+
+func (o *FileOutput) watchSignals() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, o.signals...)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ch:
+				if err := o.Reopen(); err != nil && err != ErrOutputClosed {
+					reportError(fmt.Errorf("reopening file: %w", err))
+				}
+			case <-o.stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,78 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewFileOutputError(t *testing.T) {
+	var out Output
+	out, err := NewFileOutput(filepath.Join(t.TempDir(), "missing", "app.log"), false, F_Std, L_Info, T_Text, true)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if out != nil {
+		t.Fatalf("output = %#v, want nil", out)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	out, err := NewFileOutput(path, false, F_Level|F_NewLine, L_Info, T_Text, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := NewLogger().Sync()
+	l.AddOutput(out)
+
+	l.Info("before")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	// logrotate's create mode
+	if err := os.WriteFile(path, nil, 0664); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.(*FileOutput).Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	l.Info("after")
+	l.Close()
+
+	for name, want := range map[string]string{path + ".1": "[INFO] before\n", path: "[INFO] after\n"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != want {
+			t.Errorf("%v = %q, want %q", name, data, want)
+		}
+	}
+	if err := out.(*FileOutput).Reopen(); err != ErrOutputClosed {
+		t.Errorf("Reopen after close = %v, want %v", err, ErrOutputClosed)
+	}
+}
+
+func TestReopenExclusive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	out, err := NewFileOutput(path, false, F_NewLine, L_Info, T_Text, true, WithExclusive())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.LogClose()
+	if _, err := NewFileOutput(path, false, F_NewLine, L_Info, T_Text, true, WithExclusive()); !os.IsExist(err) {
+		t.Errorf("opening an existing file = %v, want an exists error", err)
+	}
+
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, nil, 0664); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.(*FileOutput).Reopen(); err != nil {
+		t.Fatalf("Reopen = %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy describes which files produced by a FileOutput are kept.
+//
+// Files are considered from the most recent to the oldest and a file is
+// expired as soon as any of the limits is reached. Zero values disable limits.
+//
+// The file being written is never expired but still counts towards MaxCount and MaxSize.
+type RetentionPolicy struct {
+	// files last modified more than MaxAge ago are expired
+	MaxAge time.Duration
+
+	// maximum number of files
+	MaxCount int
+
+	// maximum total size of files in bytes
+	MaxSize int64
+
+	// if set, expired files are moved into the Archive directory instead of being
+	// removed, keeping their path relative to the directory of the output
+	// (a number is added to their name if it is already taken, ex: app-1.log).
+	// Directories are created with the permissions given to WithMkdir (0775 by default).
+	Archive string
+}
+
+// WithRetention makes FileOutput apply policy to the files it produced
+// (dated, rotated and compressed files) when it is created and after each rotation.
+//
+// Work is done in the background and failures are reported to ErrorHandler.
+func WithRetention(policy RetentionPolicy) FileOption {
+	return func(o *FileOutput) {
+		o.retention = &policy
+	}
+}
+
+type retainedFile struct {
+	path string
+	info os.FileInfo
+}
+
+// retain applies o.retention to the files produced by o
+func (o *FileOutput) retain() {
+	o.mu.Lock()
+	current := filepath.Clean(o.path)
+	o.mu.Unlock()
+
+	files, err := o.files()
+	if err != nil {
+		reportError(fmt.Errorf("retention: %w", err))
+		return
+	}
+	// most recent first
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].info.ModTime().After(files[j].info.ModTime())
+	})
+
+	var (
+		p     = o.retention
+		now   = time.Now()
+		count int
+		total int64
+	)
+	for _, file := range files {
+		count++
+		total += file.info.Size()
+		if filepath.Clean(file.path) == current {
+			continue
+		}
+		if (p.MaxAge > 0 && now.Sub(file.info.ModTime()) > p.MaxAge) ||
+			(p.MaxCount > 0 && count > p.MaxCount) ||
+			(p.MaxSize > 0 && total > p.MaxSize) {
+			// files can be expired by other processes sharing them
+			if err := o.expire(file.path); err != nil && !os.IsNotExist(err) {
+				reportError(fmt.Errorf("retention: %w", err))
+			}
+		}
+	}
+}
+
+// files returns all regular files produced by o
+func (o *FileOutput) files() ([]retainedFile, error) {
+	// names are checked against o.names as the glob also matches
+	// files of other outputs (ex: app-audit.log for app.log)
+	matches, err := filepath.Glob(strings.TrimSuffix(o.pattern, filepath.Ext(o.pattern)) + "*")
+	if err != nil {
+		return nil, err
+	}
+	var files []retainedFile
+	for _, path := range matches {
+		if !o.names.MatchString(filepath.Clean(path)) {
+			continue
+		}
+		info, err := os.Lstat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, retainedFile{path, info})
+	}
+	return files, nil
+}
+
+// expire removes or archives path and then removes the directories
+// left empty between path and the directory of the output
+func (o *FileOutput) expire(path string) error {
+	root := o.root()
+	var err error
+	if o.retention.Archive != "" {
+		var dst string
+		if dst, err = filepath.Rel(root, path); err == nil {
+			dst = filepath.Join(o.retention.Archive, dst)
+			if err = os.MkdirAll(filepath.Dir(dst), o.dirMode()); err == nil {
+				err = os.Rename(path, archiveName(dst))
+			}
+		}
+	} else {
+		err = os.Remove(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	for dir := filepath.Dir(path); dir != root && strings.HasPrefix(dir, root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// dirMode returns the permissions directories created by o have
+// (the ones given to WithMkdir or 0775)
+func (o *FileOutput) dirMode() os.FileMode {
+	if o.mkdir != 0 {
+		return o.mkdir
+	}
+	return 0775
+}
+
+// root returns the directory holding every file produced by o
+func (o *FileOutput) root() string {
+	root := filepath.Dir(o.pattern)
+	for strings.ContainsAny(root, "*?[") {
+		root = filepath.Dir(root)
+	}
+	return root
+}
+
+// archiveName returns path or, if it is taken, path with a number inserted
+// before its extension so that archived files never overwrite each other
+func archiveName(path string) string {
+	ext := filepath.Ext(strings.TrimSuffix(path, ".gz"))
+	stem := strings.TrimSuffix(strings.TrimSuffix(path, ".gz"), ext)
+	ext = path[len(stem):]
+	name := path
+	for i := 1; exists(name); i++ {
+		name = stem + "-" + strconv.Itoa(i) + ext
+	}
+	return name
+}
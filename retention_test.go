@@ -0,0 +1,144 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func touch(t *testing.T, path string, age time.Duration) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0775); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("x\n"), 0664); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	var names []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			rel, _ := filepath.Rel(dir, path)
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func checkFiles(t *testing.T, dir string, want ...string) {
+	t.Helper()
+	sort.Strings(want)
+	got := listDir(t, dir)
+	if len(got) != len(want) {
+		t.Fatalf("files = %q, want %q", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("files = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestRetentionKeepsOtherOutputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"app-2022-12-20T12-43-05.000.log",
+		"app-2022-12-20T12-43-05.000-1.log.gz",
+		"app-audit.log",
+		"app-audit-2022-12-20T12-43-05.000.log",
+		"app.log.bak",
+		"app-2022-12-20T12-43-05.000.log.gz.tmp",
+	} {
+		touch(t, filepath.Join(dir, name), 2*time.Hour)
+	}
+
+	out, err := NewRotatingFileOutput(filepath.Join(dir, "app.log"), 1<<20, F_Std, L_Info, T_Text,
+		WithRetention(RetentionPolicy{MaxAge: time.Hour}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := out.LogClose(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir,
+		"app.log",
+		"app-audit.log",
+		"app-audit-2022-12-20T12-43-05.000.log",
+		"app.log.bak",
+		"app-2022-12-20T12-43-05.000.log.gz.tmp",
+	)
+}
+
+func TestRetentionTimedAndTemplate(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"app-2022-12-20T12-00-00.log",
+		"app-2022-12-20T12-00-00-2022-12-20T12-43-05.000.log.gz",
+		"app-extra-2022-12-20T12-00-00.log",
+		"job-20221220-3.log",
+		"job-20221220-3-2022-12-20T12-43-05.000.log",
+		"job-2022-3.log",
+	} {
+		touch(t, filepath.Join(dir, name), 2*time.Hour)
+	}
+
+	out, err := NewTimedFileOutput(filepath.Join(dir, "app.log"), Daily, F_Std, L_Info, T_Text,
+		WithRetention(RetentionPolicy{MaxAge: time.Hour}), WithSymlink(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := out.LogClose(); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := NewFileOutput(filepath.Join(dir, "job.log"), false, F_Std, L_Info, T_Text, true,
+		WithNameTemplate("job-%Y%m%d-%n.log"), WithRetention(RetentionPolicy{MaxAge: time.Hour}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tmpl.LogClose(); err != nil {
+		t.Fatal(err)
+	}
+
+	start, _ := period(time.Now(), Daily)
+	checkFiles(t, dir,
+		"app-extra-2022-12-20T12-00-00.log",
+		"job-2022-3.log",
+		filepath.Base(periodName("app.log", start)),
+		"job-"+time.Now().Format("20060102")+"-4.log",
+	)
+}
+
+func TestRetentionArchiveSameName(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "old")
+	touch(t, filepath.Join(dir, "2022", "12", "19", "app.log"), 3*time.Hour)
+	touch(t, filepath.Join(dir, "2022", "12", "20", "app.log"), 2*time.Hour)
+	// already archived by a previous run
+	touch(t, filepath.Join(archive, "2022", "12", "20", "app.log"), 4*time.Hour)
+
+	out, err := NewFileOutput(filepath.Join(dir, "app.log"), false, F_Std, L_Info, T_Text, true,
+		WithNameTemplate("%Y/%m/%d/%f"), WithMkdir(0775),
+		WithRetention(RetentionPolicy{MaxAge: time.Hour, Archive: archive}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := out.LogClose(); err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, dir, time.Now().Format("2006/01/02")+"/app.log")
+	checkFiles(t, archive, "2022/12/19/app.log", "2022/12/20/app.log", "2022/12/20/app-1.log")
+}
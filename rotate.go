@@ -0,0 +1,236 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// layout used to timestamp rotated files
+const rotateLayout = "2006-01-02T15-04-05.000"
+
+// regexps matching the time stamps added by periodName and backupName
+const (
+	periodRegexp = `-\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}`
+	rotateRegexp = periodRegexp + `\.\d{3}(?:-\d+)?`
+)
+
+// NewRotatingFileOutput is similar to NewFileOutput but once the file grows
+// past maxSize bytes, it is renamed with the time of rotation appended to its
+// name (ex: app.log -> app-2022-12-20T12-43-05.000.log) and a new file is
+// created at path.
+//
+// The file is always opened in append mode so that restarting a program
+// keeps on filling the current file.
+func NewRotatingFileOutput(path string, maxSize int64, flags int, logLevel LogLevel, outputType OutputType, opts ...FileOption) (Output, error) {
+	return NewFileOutput(path, false, flags, logLevel, outputType, true, append(opts, WithMaxSize(maxSize))...)
+}
+
+// WithMaxSize makes FileOutput rotate its file once it grows past n bytes;
+// n <= 0 disables size based rotation.
+func WithMaxSize(n int64) FileOption {
+	return func(o *FileOutput) {
+		o.maxSize = n
+	}
+}
+
+// rotate renames the current file and opens a new one in its place, or
+// switches to the next file if the name template gives a different name.
+// If anything fails the current file is kept so that no entry is lost.
+//
+// o.mu must be held.
+func (o *FileOutput) rotate() error {
+	if o.template != "" {
+		o.seq++
+		if path := o.newName(time.Now()); path != o.path {
+			return o.switchTo(path)
+		}
+		o.seq--
+	}
+	backup := backupName(o.path, time.Now())
+	if err := os.Rename(o.path, backup); err != nil {
+		return err
+	}
+	f, err := o.open(o.path, appendFlags)
+	if err != nil {
+		os.Rename(backup, o.path)
+		return err
+	}
+	err = o.replace(f)
+	o.rotated(backup)
+	return err
+}
+
+// backupName returns an unused name for a rotated file by inserting
+// t between the file name and its extension
+func backupName(path string, t time.Time) string {
+	ext := filepath.Ext(path)
+	stem := strings.TrimSuffix(path, ext) + "-" + t.Format(rotateLayout)
+	name := stem + ext
+	for i := 1; ; i++ {
+		if !exists(name) && !exists(name+".gz") {
+			return name
+		}
+		name = stem + "-" + strconv.Itoa(i) + ext
+	}
+}
+
+const (
+	Hourly = time.Hour
+	Daily  = 24 * time.Hour
+)
+
+// NewTimedFileOutput is similar to NewFileOutput but starts a new file each time
+// a wall-clock boundary multiple of every is crossed (see Hourly and Daily).
+//
+// Files are named after the start of the period they hold
+// (ex: app.log -> app-2022-12-20T12-00-00.log) and a symlink named 'current'
+// followed by path's extension (ex: current.log) is kept pointing to the
+// file being written, this can be changed with WithSymlink.
+//
+// Intervals dividing a day are aligned on local midnight, other intervals are
+// aligned on the zero time (see time.Time.Truncate).
+func NewTimedFileOutput(path string, every time.Duration, flags int, logLevel LogLevel, outputType OutputType, opts ...FileOption) (Output, error) {
+	var link = filepath.Join(filepath.Dir(path), "current"+filepath.Ext(path))
+	opts = append([]FileOption{WithSymlink(link)}, opts...)
+	return NewFileOutput(path, false, flags, logLevel, outputType, true, append(opts, WithRotateEvery(every))...)
+}
+
+// WithRotateEvery makes FileOutput start a new file every d; d <= 0 disables
+// time based rotation.
+//
+// see NewTimedFileOutput
+func WithRotateEvery(d time.Duration) FileOption {
+	return func(o *FileOutput) {
+		o.every = d
+	}
+}
+
+// WithSymlink makes FileOutput keep a symlink at path pointing to the file
+// being written; an empty path disables it.
+func WithSymlink(path string) FileOption {
+	return func(o *FileOutput) {
+		o.symlink = path
+	}
+}
+
+// rollover closes the current file and opens the one of the period t is in.
+//
+// o.mu must be held.
+func (o *FileOutput) rollover(t time.Time) error {
+	start, next := period(t, o.every)
+	// on failure the previous file is kept until next period
+	o.next = next
+	o.seq++
+	if path := o.newName(start); path != o.path {
+		return o.switchTo(path)
+	}
+	return nil
+}
+
+// switchTo closes the current file and opens path in its place.
+//
+// o.mu must be held.
+func (o *FileOutput) switchTo(path string) error {
+	f, err := o.open(path, appendFlags)
+	if err != nil {
+		return err
+	}
+	oldPath := o.path
+	o.path = path
+	err = o.replace(f)
+	o.rotated(oldPath)
+	if o.symlink != "" {
+		if e := o.link(); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// link atomically points o.symlink to the file being written.
+//
+// o.mu must be held.
+func (o *FileOutput) link() error {
+	target := o.path
+	if rel, err := filepath.Rel(filepath.Dir(o.symlink), o.path); err == nil {
+		target = rel
+	}
+	tmp := o.symlink + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, o.symlink)
+}
+
+// period returns the bounds of the period of length d t is in
+func period(t time.Time, d time.Duration) (start, end time.Time) {
+	if Daily%d != 0 {
+		start = t.Truncate(d)
+		return start, start.Add(d)
+	}
+	year, month, day := t.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	start = midnight.Add(t.Sub(midnight) / d * d)
+	end = start.Add(d)
+	// days are not always 24h long
+	if tomorrow := midnight.AddDate(0, 0, 1); end.After(tomorrow) {
+		end = tomorrow
+	}
+	return start, end
+}
+
+// newName returns the path of a file started at t
+// (or holding the period starting at t for timed outputs)
+func (o *FileOutput) newName(t time.Time) string {
+	if o.template != "" {
+		return expandTemplate(o.base, o.template, t, o.seq, false)
+	}
+	if o.every > 0 {
+		return periodName(o.base, t)
+	}
+	return o.base
+}
+
+// periodName returns the name of the file holding the period starting at start
+func periodName(path string, start time.Time) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + start.Format("2006-01-02T15-04-05") + ext
+}
+
+// nameRegexp returns a regexp matching the names of the files produced by o, names
+// being a regexp matching the names returned by newName (ignoring periods).
+//
+// Names may be followed by the time stamp of their period for timed outputs, by the
+// time stamp of their rotation and by a '.gz' suffix, all before their extension.
+func (o *FileOutput) nameRegexp(names string) *regexp.Regexp {
+	ext := regexp.QuoteMeta(filepath.Ext(o.path))
+	if strings.HasSuffix(names, ext) {
+		names = strings.TrimSuffix(names, ext)
+	} else {
+		ext = ""
+	}
+	if o.every > 0 && o.template == "" {
+		names += "(?:" + periodRegexp + ")?"
+	}
+	return regexp.MustCompile("^" + names + "(?:" + rotateRegexp + ")?" + ext + `(?:\.gz)?$`)
+}
+
+// dirRegexp returns a regexp matching the directory of path, as a prefix of cleaned paths
+func dirRegexp(path string) string {
+	dir := filepath.Dir(path)
+	if dir == "." {
+		return ""
+	}
+	sep := string(filepath.Separator)
+	return regexp.QuoteMeta(strings.TrimSuffix(dir, sep) + sep)
+}
+
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+	return !os.IsNotExist(err)
+}
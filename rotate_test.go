@@ -0,0 +1,116 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupName(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	now := time.Date(2022, 12, 20, 12, 43, 5, 123e6, time.Local)
+
+	want := filepath.Join(dir, "app-2022-12-20T12-43-05.123.log")
+	if got := backupName(path, now); got != want {
+		t.Fatalf("backupName = %v, want %v", got, want)
+	}
+	touch(t, want, 0)
+	touch(t, filepath.Join(dir, "app-2022-12-20T12-43-05.123-1.log.gz"), 0)
+	if got, want := backupName(path, now), filepath.Join(dir, "app-2022-12-20T12-43-05.123-2.log"); got != want {
+		t.Fatalf("backupName = %v, want %v", got, want)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	out, err := NewRotatingFileOutput(filepath.Join(dir, "app.log"), 100, F_NewLine, L_Info, T_Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := out.(*FileOutput)
+	var line = strings.Repeat("x", 39) // 40 bytes with the line break
+	for i := 0; i < 10; i++ {
+		if err := out.Log(&LogEntry{Level: L_Info, Msg: line}); err != nil {
+			t.Fatal(err)
+		}
+		if f.size >= 100 {
+			t.Fatalf("size = %v after rotation", f.size)
+		}
+		if info, err := os.Stat(f.path); err != nil || info.Size() != f.size {
+			t.Fatalf("size = %v, want the size of the current file (%v)", f.size, info)
+		}
+	}
+	if err := out.LogClose(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3 entries per file, 3 backups and the current file with the last one
+	backup := regexp.MustCompile(`^app-\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2}\.\d{3}(-\d+)?\.log$`)
+	files := listDir(t, dir)
+	if len(files) != 4 {
+		t.Fatalf("files = %q, want 3 backups and app.log", files)
+	}
+	var lines int
+	for _, name := range files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := bytes.Count(data, []byte("\n"))
+		lines += n
+		switch {
+		case name == "app.log":
+			if n != 1 {
+				t.Errorf("app.log has %v entries, want 1", n)
+			}
+		case backup.MatchString(name):
+			if n != 3 {
+				t.Errorf("%v has %v entries, want 3", name, n)
+			}
+		default:
+			t.Errorf("unexpected file %v", name)
+		}
+	}
+	if lines != 10 {
+		t.Errorf("%v entries written, want 10", lines)
+	}
+}
+
+func TestRolloverSymlink(t *testing.T) {
+	dir := t.TempDir()
+	out, err := NewTimedFileOutput(filepath.Join(dir, "app.log"), Hourly, F_NewLine, L_Info, T_Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.LogClose()
+	link := filepath.Join(dir, "current.log")
+
+	now := time.Now()
+	start, next := period(now, Hourly)
+	for _, tt := range []struct {
+		time   time.Time
+		target string
+	}{
+		{now, filepath.Base(periodName("app.log", start))},
+		{next, filepath.Base(periodName("app.log", next))},
+	} {
+		if err := out.Log(&LogEntry{Time: tt.time, Level: L_Info, Msg: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+		target, err := os.Readlink(link)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target != tt.target {
+			t.Errorf("current.log -> %v, want %v", target, tt.target)
+		}
+		if data, err := os.ReadFile(link); err != nil || string(data) != "hi\n" {
+			t.Errorf("current.log holds %q (%v), want the last entry", data, err)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package log
+
+import (
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrSecureUnsupported is returned by NewFileOutput when WithNoFollow or
+	// WithOwnerCheck are used on a platform where they are not supported
+	ErrSecureUnsupported = fmt.Errorf("secure file options are not supported on this platform")
+
+	// ErrSymlink is returned when opening a symlink with WithNoFollow
+	ErrSymlink = fmt.Errorf("file is a symlink")
+
+	// ErrFileOwner is returned when opening a file owned by another user with WithOwnerCheck
+	ErrFileOwner = fmt.Errorf("file is owned by another user")
+)
+
+// The following options apply to every file opened by FileOutput, including
+// files started on rotation and files reopened (see Reopen), so errors can also
+// be returned by Log calls. Directory permissions are set with WithMkdir.
+
+// WithFileMode sets the permissions files are created with (0664 by default),
+// before umask. Compressed files (see WithCompress) are created with the same permissions.
+func WithFileMode(perm os.FileMode) FileOption {
+	return func(o *FileOutput) {
+		o.perm = perm
+	}
+}
+
+// WithNoFollow makes FileOutput refuse to open a file that is a symlink (O_NOFOLLOW).
+//
+// It is only supported on Linux.
+func WithNoFollow() FileOption {
+	return func(o *FileOutput) {
+		o.noFollow = true
+	}
+}
+
+// WithOwnerCheck makes FileOutput refuse to open a file that is not owned
+// by the effective user of the process.
+//
+// It is only supported on Linux.
+func WithOwnerCheck() FileOption {
+	return func(o *FileOutput) {
+		o.checkOwner = true
+	}
+}
+
+// WithExclusive makes FileOutput refuse to start if its file already exists (O_EXCL).
+//
+// It only applies to the file opened by the constructor, so that the file
+// can be opened again after being rotated by an external tool (see Reopen).
+func WithExclusive() FileOption {
+	return func(o *FileOutput) {
+		o.exclusive = true
+	}
+}
+
+func isSymlink(path string) bool {
+	info, err := os.Lstat(path)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
@@ -0,0 +1,22 @@
+package log
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	secureSupported = true
+	oNoFollow       = syscall.O_NOFOLLOW
+)
+
+func checkOwner(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); !ok || int(st.Uid) != os.Geteuid() {
+		return ErrFileOwner
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCompressPermissions(t *testing.T) {
+	defer syscall.Umask(syscall.Umask(0))
+	dir := t.TempDir()
+	out, err := NewFileOutput(filepath.Join(dir, "a.log"), false, F_NewLine, L_Info, T_Text, true,
+		WithFileMode(0600), WithNoFollow(), WithCompress())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.LogClose()
+	f := out.(*FileOutput)
+
+	src := filepath.Join(dir, "b.log")
+	touch(t, src, 0)
+	// a symlink planted at the temporary name must not be followed
+	victim := filepath.Join(dir, "victim")
+	touch(t, victim, 0)
+	if err := os.Symlink(victim, src+".gz.tmp"); err != nil {
+		t.Fatal(err)
+	}
+	name, err := f.compressFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(name); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0600 {
+		t.Errorf("%v mode = %v, want 0600", name, info.Mode())
+	}
+	if data, err := os.ReadFile(victim); err != nil || string(data) != "x\n" {
+		t.Errorf("symlink target = %q (%v), want it untouched", data, err)
+	}
+}
+
+func TestRetentionArchiveDirMode(t *testing.T) {
+	defer syscall.Umask(syscall.Umask(0))
+	dir := t.TempDir()
+	archive := filepath.Join(t.TempDir(), "old")
+	touch(t, filepath.Join(dir, "2022", "app.log"), 2*time.Hour)
+	out, err := NewFileOutput(filepath.Join(dir, "app.log"), false, F_Std, L_Info, T_Text, true,
+		WithNameTemplate("%Y/%f"), WithMkdir(0700),
+		WithRetention(RetentionPolicy{MaxAge: time.Hour, Archive: archive}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := out.LogClose(); err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []string{archive, filepath.Join(archive, "2022")} {
+		if info, err := os.Stat(d); err != nil {
+			t.Error(err)
+		} else if info.Mode().Perm() != 0700 {
+			t.Errorf("%v mode = %v, want 0700", d, info.Mode())
+		}
+	}
+}
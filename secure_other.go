@@ -0,0 +1,14 @@
+//go:build !linux
+
+package log
+
+import "os"
+
+const (
+	secureSupported = false
+	oNoFollow       = 0
+)
+
+func checkOwner(f *os.File) error {
+	return ErrSecureUnsupported
+}
@@ -0,0 +1,80 @@
+package log
+
+import (
+	"fmt"
+	"time"
+)
+
+// By default FileOutput never syncs its file, leaving it to the operating system.
+// The following options can be combined, in which case the file is synced as
+// soon as any of them requires it.
+
+// WithSyncEvery makes FileOutput sync its file to disk every n entries
+// (WithSyncEvery(1) syncs after each entry).
+func WithSyncEvery(n int) FileOption {
+	return func(o *FileOutput) {
+		o.syncEvery = n
+	}
+}
+
+// WithSyncInterval makes FileOutput sync its file to disk every d
+// if any entry has been written since last sync.
+func WithSyncInterval(d time.Duration) FileOption {
+	return func(o *FileOutput) {
+		o.syncInterval = d
+	}
+}
+
+// WithSyncLevel makes FileOutput sync its file to disk after each entry
+// at or above level.
+//
+// As Fatal calls are Sync, WithSyncLevel(L_Fatal) ensures fatal entries are on disk
+// before the program exits.
+func WithSyncLevel(level LogLevel) FileOption {
+	return func(o *FileOutput) {
+		o.syncLevel = &level
+	}
+}
+
+// syncs returns wether any sync option is set
+func (o *FileOutput) syncs() bool {
+	return o.syncEvery > 0 || o.syncInterval > 0 || o.syncLevel != nil
+}
+
+// maybeSync is called after each entry written and syncs the file if required.
+//
+// o.mu must be held.
+func (o *FileOutput) maybeSync(level LogLevel) error {
+	if !o.syncs() {
+		return nil
+	}
+	o.unsynced++
+	if (o.syncEvery > 0 && o.unsynced >= o.syncEvery) ||
+		(o.syncLevel != nil && o.syncLevel.Permits(level)) {
+		o.unsynced = 0
+		return o.f.Sync()
+	}
+	return nil
+}
+
+func (o *FileOutput) syncPeriodically() {
+	ticker := time.NewTicker(o.syncInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				o.mu.Lock()
+				if !o.closed && o.unsynced > 0 {
+					o.unsynced = 0
+					if err := o.f.Sync(); err != nil {
+						reportError(fmt.Errorf("syncing file: %w", err))
+					}
+				}
+				o.mu.Unlock()
+			case <-o.stop:
+				return
+			}
+		}
+	}()
+}
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// unsynced returns the number of entries written to o since its file was last synced
+func unsynced(o Output) int {
+	f := o.(*FileOutput)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.unsynced
+}
+
+func TestSyncEvery(t *testing.T) {
+	out, err := NewFileOutput(filepath.Join(t.TempDir(), "app.log"), false, F_Std, L_Info, T_Text, true, WithSyncEvery(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.LogClose()
+	for i, want := range []int{1, 2, 0, 1, 2, 0} {
+		if err := out.Log(&LogEntry{Level: L_Info, Msg: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+		if got := unsynced(out); got != want {
+			t.Fatalf("entry %v: %v entries not synced, want %v", i, got, want)
+		}
+	}
+}
+
+func TestSyncLevel(t *testing.T) {
+	out, err := NewFileOutput(filepath.Join(t.TempDir(), "app.log"), false, F_Std, L_Debug, T_Text, true, WithSyncLevel(L_Error))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.LogClose()
+	for i, tt := range []struct {
+		level LogLevel
+		want  int
+	}{
+		{L_Debug, 1}, {L_Info, 2}, {L_Warn, 3}, {L_Error, 0}, {L_Info, 1}, {L_Fatal, 0},
+	} {
+		if err := out.Log(&LogEntry{Level: tt.level, Msg: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+		if got := unsynced(out); got != tt.want {
+			t.Fatalf("entry %v (%v): %v entries not synced, want %v", i, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestSyncInterval(t *testing.T) {
+	out, err := NewFileOutput(filepath.Join(t.TempDir(), "app.log"), false, F_Std, L_Info, T_Text, true, WithSyncInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.LogClose()
+	for i := 0; i < 2; i++ {
+		if err := out.Log(&LogEntry{Level: L_Info, Msg: "hi"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for unsynced(out) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("file not synced by the interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,199 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithNameTemplate makes FileOutput name its files by expanding tmpl, relatively to
+// the directory of the path given to the constructor (unless tmpl is absolute).
+//
+// The following strftime-like tokens are expanded:
+//
+//	%Y  year (2022)           %y  year without century (22)
+//	%m  month (01-12)         %d  day of month (01-31)
+//	%j  day of year (001-366) %H  hour (00-23)
+//	%M  minute (00-59)        %S  second (00-59)
+//	%L  millisecond (000-999) %s  unix time in seconds
+//	%h  hostname              %p  process id
+//	%n  sequence number of the file, starting after the highest
+//	    one of existing files (or at 0) and incremented each
+//	    time a new file is started
+//	%f  file name of the path given to the constructor
+//	%%  a literal '%'
+//
+// Time tokens are expanded with the time the file is started, or with the start of its
+// period for timed outputs (see NewTimedFileOutput).
+//
+// If a template with no token expanding differently on rotation is used with WithMaxSize,
+// files are rotated by renaming them (see NewRotatingFileOutput).
+//
+// ex: WithNameTemplate("%Y/%m/%d/%H-%M-%S_%h_%f") with path "logs/app.log" writes
+// to "logs/2022/12/20/12-43-05_myhost_app.log"
+func WithNameTemplate(tmpl string) FileOption {
+	return func(o *FileOutput) {
+		o.template = tmpl
+	}
+}
+
+// WithMkdir makes FileOutput create missing parent directories of its files with perm.
+func WithMkdir(perm os.FileMode) FileOption {
+	return func(o *FileOutput) {
+		o.mkdir = perm
+	}
+}
+
+var (
+	hostOnce sync.Once
+	hostname string
+)
+
+func getHostname() string {
+	hostOnce.Do(func() {
+		hostname, _ = os.Hostname()
+		if hostname == "" {
+			hostname = "localhost"
+		}
+	})
+	return hostname
+}
+
+// expandTemplate expands tmpl relatively to path, if glob is true,
+// tokens are expanded to '*' so that the result matches any expansion of tmpl
+func expandTemplate(path string, tmpl string, t time.Time, seq int, glob bool) string {
+	dir, name := filepath.Split(path)
+	var b = make([]byte, 0, len(tmpl)+len(name))
+	for i := 0; i < len(tmpl); i++ {
+		if tmpl[i] != '%' || i == len(tmpl)-1 {
+			b = append(b, tmpl[i])
+			continue
+		}
+		i++
+		switch c := tmpl[i]; {
+		case c == '%':
+			b = append(b, '%')
+		case c == 'f':
+			b = append(b, name...)
+		case glob:
+			if strings.IndexByte("YymdjHMSLshpn", c) == -1 {
+				b = append(b, '%', c)
+			} else if len(b) == 0 || b[len(b)-1] != '*' {
+				b = append(b, '*')
+			}
+		default:
+			b = appendToken(b, c, t, seq)
+		}
+	}
+
+	res := string(b)
+	if !filepath.IsAbs(res) {
+		res = filepath.Join(dir, res)
+	}
+	return res
+}
+
+// templateRegexp returns a regexp matching any expansion of tmpl relatively to path
+// (cleaned as done by expandTemplate), the sequence number being its only capturing group
+func templateRegexp(path string, tmpl string) string {
+	dir, name := filepath.Split(path)
+	var b = make([]byte, 0, len(tmpl)+len(name))
+	for i := 0; i < len(tmpl); i++ {
+		if tmpl[i] != '%' || i == len(tmpl)-1 {
+			b = append(b, tmpl[i])
+			continue
+		}
+		i++
+		switch c := tmpl[i]; {
+		case c == '%':
+			b = append(b, '%')
+		case c == 'f':
+			b = append(b, name...)
+		case strings.IndexByte("YymdjHMSLshpn", c) != -1:
+			// placeholder replaced once the path is joined and quoted
+			b = append(b, 0, c)
+		default:
+			b = append(b, '%', c)
+		}
+	}
+
+	res := string(b)
+	if !filepath.IsAbs(res) {
+		res = filepath.Join(dir, res)
+	}
+	res = regexp.QuoteMeta(res)
+	for _, c := range "YymdjHMSLshpn" {
+		var re string
+		switch c {
+		case 'Y':
+			re = `\d{4}`
+		case 'y', 'm', 'd', 'H', 'M', 'S':
+			re = `\d{2}`
+		case 'j', 'L':
+			re = `\d{3}`
+		case 's', 'p':
+			re = `\d+`
+		case 'h':
+			re = regexp.QuoteMeta(getHostname())
+		case 'n':
+			re = `(\d+)`
+		}
+		res = strings.ReplaceAll(res, "\x00"+string(c), re)
+	}
+	return res
+}
+
+// lastSeq returns the highest sequence number (%n) of the files produced by o,
+// or -1 if there is none
+func (o *FileOutput) lastSeq() int {
+	var last = -1
+	files, _ := o.files()
+	for _, file := range files {
+		m := o.names.FindStringSubmatch(filepath.Clean(file.path))
+		if len(m) < 2 {
+			continue
+		}
+		if n, err := strconv.Atoi(m[1]); err == nil && n > last {
+			last = n
+		}
+	}
+	return last
+}
+
+func appendToken(b []byte, c byte, t time.Time, seq int) []byte {
+	switch c {
+	case 'Y':
+		appendInt(&b, t.Year(), 4)
+	case 'y':
+		appendInt(&b, t.Year()%100, 2)
+	case 'm':
+		appendInt(&b, int(t.Month()), 2)
+	case 'd':
+		appendInt(&b, t.Day(), 2)
+	case 'j':
+		appendInt(&b, t.YearDay(), 3)
+	case 'H':
+		appendInt(&b, t.Hour(), 2)
+	case 'M':
+		appendInt(&b, t.Minute(), 2)
+	case 'S':
+		appendInt(&b, t.Second(), 2)
+	case 'L':
+		appendInt(&b, t.Nanosecond()/1e6, 3)
+	case 's':
+		b = strconv.AppendInt(b, t.Unix(), 10)
+	case 'h':
+		b = append(b, getHostname()...)
+	case 'p':
+		appendInt(&b, os.Getpid(), 1)
+	case 'n':
+		appendInt(&b, seq, 1)
+	default:
+		b = append(b, '%', c)
+	}
+	return b
+}
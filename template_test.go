@@ -0,0 +1,33 @@
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTemplateSequence(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	for i, want := range []string{"app-0.log", "app-1.log", "app-2.log"} {
+		// previous files must not be truncated
+		out, err := NewFileOutput(path, false, F_NewLine, L_Info, T_Text, false, WithNameTemplate("app-%n.log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := filepath.Base(out.(*FileOutput).path); got != want {
+			t.Errorf("run %v writes to %v, want %v", i, got, want)
+		}
+		if err := out.Log(&LogEntry{Level: L_Info, Msg: "entry"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := out.LogClose(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"app-0.log", "app-1.log", "app-2.log"} {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(data) != "entry\n" {
+			t.Errorf("%v = %q, %v", name, data, err)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package log
+
+import (
+	"strconv"
+	"time"
+)
+
+// Time layouts formatted without going through time.Time.AppendFormat, any other
+// time.Time layout can also be used in a TimeFormat.
+const (
+	// dd/mm/yyyy hh:mm:ss, used by TextLogFunc
+	TimeText = ""
+
+	TimeRFC3339     = time.RFC3339
+	TimeRFC3339Nano = time.RFC3339Nano
+
+	// ISO 8601 with milliseconds
+	TimeISOMilli = "2006-01-02T15:04:05.000Z07:00"
+
+	// seconds since unix epoch
+	TimeUnix = "unix"
+
+	// milliseconds since unix epoch
+	TimeUnixMilli = "unixmilli"
+)
+
+// TimeFormat describes how the time of entries is formatted
+//
+// The zero value is the format used by TextLogFunc
+type TimeFormat struct {
+	// one of the Time* constants or a time.Time layout
+	Layout string
+
+	// location time is converted to before formatting; nil means local time
+	// (see time.UTC, time.Local and time.LoadLocation)
+	Location *time.Location
+}
+
+// appendTime appends t formatted according to tf to buf, micro adds
+// microseconds to TimeText
+func (tf TimeFormat) appendTime(buf *[]byte, t time.Time, micro bool) {
+	if tf.Location != nil {
+		t = t.In(tf.Location)
+	}
+	switch tf.Layout {
+	case TimeText:
+		year, month, day := t.Date()
+		appendInt(buf, day, 2)
+		*buf = append(*buf, '/')
+		appendInt(buf, int(month), 2)
+		*buf = append(*buf, '/')
+		appendInt(buf, year, 4)
+		*buf = append(*buf, ' ')
+		appendClock(buf, t)
+		if micro {
+			*buf = append(*buf, '.')
+			appendInt(buf, t.Nanosecond()/1000, 6)
+		}
+	case TimeRFC3339:
+		appendISO(buf, t, tf.Layout, 0, false)
+	case TimeISOMilli:
+		appendISO(buf, t, tf.Layout, 3, false)
+	case TimeRFC3339Nano:
+		appendISO(buf, t, tf.Layout, 9, true)
+	case TimeUnix:
+		*buf = strconv.AppendInt(*buf, t.Unix(), 10)
+	case TimeUnixMilli:
+		*buf = strconv.AppendInt(*buf, t.UnixMilli(), 10)
+	default:
+		*buf = t.AppendFormat(*buf, tf.Layout)
+	}
+}
+
+// appendClock appends hh:mm:ss
+func appendClock(buf *[]byte, t time.Time) {
+	hour, min, sec := t.Clock()
+	appendInt(buf, hour, 2)
+	*buf = append(*buf, ':')
+	appendInt(buf, min, 2)
+	*buf = append(*buf, ':')
+	appendInt(buf, sec, 2)
+}
+
+// appendISO appends t in RFC 3339 format with digits fractional second digits,
+// trailing zeros are removed if trim is true. It gives the same result as formatting
+// t with layout.
+func appendISO(buf *[]byte, t time.Time, layout string, digits int, trim bool) {
+	if year := t.Year(); year < 0 || year > 9999 {
+		// not representable without going through time's own formatting
+		*buf = t.AppendFormat(*buf, layout)
+		return
+	}
+	year, month, day := t.Date()
+	appendInt(buf, year, 4)
+	*buf = append(*buf, '-')
+	appendInt(buf, int(month), 2)
+	*buf = append(*buf, '-')
+	appendInt(buf, day, 2)
+	*buf = append(*buf, 'T')
+	appendClock(buf, t)
+
+	if digits != 0 {
+		frac := t.Nanosecond()
+		for i := digits; i < 9; i++ {
+			frac /= 10
+		}
+		n := digits
+		if trim {
+			for n > 0 && frac%10 == 0 {
+				frac /= 10
+				n--
+			}
+		}
+		if n != 0 {
+			*buf = append(*buf, '.')
+			appendInt(buf, frac, n)
+		}
+	}
+
+	_, offset := t.Zone()
+	if offset == 0 {
+		*buf = append(*buf, 'Z')
+		return
+	}
+	// seconds of the offset are dropped, as done by time
+	zone := offset / 60
+	if zone < 0 {
+		*buf = append(*buf, '-')
+		zone = -zone
+	} else {
+		*buf = append(*buf, '+')
+	}
+	appendInt(buf, zone/60, 2)
+	*buf = append(*buf, ':')
+	appendInt(buf, zone%60, 2)
+}
+
+// WithTimeFormat makes a text FileOutput format time with tf (see NewTextLogFunc),
+// it has no effect on other output types.
+func WithTimeFormat(tf TimeFormat) FileOption {
+	return func(o *FileOutput) {
+		if o.outputType == T_Text {
+			o.LogFunc, o.outputType = NewTextLogFunc(tf)
+		}
+	}
+}
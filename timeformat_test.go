@@ -0,0 +1,59 @@
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAppendTimeMatchesFormat(t *testing.T) {
+	zones := []*time.Location{
+		time.UTC,
+		time.FixedZone("", 3600),
+		time.FixedZone("", -(3*3600 + 30*60)),
+		time.FixedZone("", 5*3600+45*60),
+		time.FixedZone("", -30), // seconds of offsets are dropped
+		time.FixedZone("", 14*3600),
+	}
+	times := []time.Time{
+		time.Date(2022, 12, 20, 12, 43, 5, 0, time.UTC),
+		time.Date(2022, 12, 20, 12, 43, 5, 123456789, time.UTC),
+		time.Date(2022, 12, 20, 12, 43, 5, 120000000, time.UTC),
+		time.Date(2022, 12, 20, 12, 43, 5, 100, time.UTC),
+		time.Date(2022, 12, 20, 12, 43, 5, 999999999, time.UTC),
+		time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(9999, 12, 31, 23, 59, 59, 500000000, time.UTC),
+		time.Date(12000, 1, 1, 0, 0, 0, 10, time.UTC),
+	}
+	for _, layout := range []string{TimeRFC3339, TimeRFC3339Nano, TimeISOMilli} {
+		for _, zone := range zones {
+			for _, at := range times {
+				var buf []byte
+				TimeFormat{Layout: layout, Location: zone}.appendTime(&buf, at, false)
+				if want := at.In(zone).Format(layout); string(buf) != want {
+					t.Errorf("%q of %v: got %v, want %v", layout, at.In(zone), string(buf), want)
+				}
+			}
+		}
+	}
+}
+
+func TestAppendTimeText(t *testing.T) {
+	at := time.Date(2022, 2, 3, 4, 5, 6, 7008000, time.UTC)
+	for _, tt := range []struct {
+		tf    TimeFormat
+		micro bool
+		want  string
+	}{
+		{TimeFormat{Location: time.UTC}, false, "03/02/2022 04:05:06"},
+		{TimeFormat{Location: time.UTC}, true, "03/02/2022 04:05:06.007008"},
+		{TimeFormat{Layout: TimeUnix}, false, "1643861106"},
+		{TimeFormat{Layout: TimeUnixMilli}, false, "1643861106007"},
+		{TimeFormat{Layout: time.Kitchen, Location: time.UTC}, false, "4:05AM"},
+	} {
+		var buf []byte
+		tt.tf.appendTime(&buf, at, tt.micro)
+		if string(buf) != tt.want {
+			t.Errorf("%+v: got %v, want %v", tt.tf, string(buf), tt.want)
+		}
+	}
+}